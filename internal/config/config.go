@@ -0,0 +1,134 @@
+// Package config loads rss-to-podcast's YAML configuration file into a
+// typed Config struct.
+package config
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServerConfig configures the `serve` subcommand, which publishes
+// already-synthesized episodes as itunes-compatible podcast RSS feeds.
+type ServerConfig struct {
+	// BaseURL is the externally reachable origin (e.g.
+	// "https://podcasts.example.com") used to build enclosure and feed
+	// URLs.
+	BaseURL string `yaml:"base_url"`
+
+	// Listen is the address the HTTP server binds to, e.g. ":8080".
+	Listen string `yaml:"listen"`
+
+	// FeedsDir is the root directory containing one subdirectory per
+	// feed; the subdirectory name is used as the feed's slug.
+	FeedsDir string `yaml:"feeds_dir"`
+
+	// BasicAuthUser/BasicAuthPass, if both set, require HTTP basic auth
+	// on every request.
+	BasicAuthUser string `yaml:"basic_auth_user,omitempty"`
+	BasicAuthPass string `yaml:"basic_auth_pass,omitempty"`
+
+	// TTS configures the on-demand POST /tts endpoint.
+	TTS TTSConfig `yaml:"tts"`
+}
+
+// TTSConfig protects the paid Google TTS backend behind the on-demand
+// POST /tts endpoint from runaway or abusive callers.
+type TTSConfig struct {
+	// OutputDir is the directory synthesized on-demand audio is written
+	// to and served from, as a subdirectory of Server.FeedsDir named
+	// OutputDir.
+	OutputDir string `yaml:"output_dir"`
+
+	// RequestsPerSecond caps the aggregate rate of accepted /tts
+	// requests across all callers. Zero disables the endpoint.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+
+	// Burst is the maximum number of /tts requests allowed in a single
+	// burst above RequestsPerSecond.
+	Burst int `yaml:"burst"`
+
+	// QuotaPerCallerPerDay caps the number of /tts requests a single
+	// caller (identified by remote IP) may make in a 24h window that
+	// starts at the caller's first request and resets once it has
+	// passed. Zero means unlimited.
+	QuotaPerCallerPerDay int `yaml:"quota_per_caller_per_day"`
+}
+
+// LogConfig configures the package-level slog.Logger used throughout the
+// program.
+type LogConfig struct {
+	// Level is one of "debug", "info", "warn" or "error". Defaults to
+	// "info" when empty.
+	Level string `yaml:"level"`
+
+	// Format is "text" (default) or "json".
+	Format string `yaml:"format"`
+
+	// Output is "stderr" (default) or "file". When "file", OutputPath
+	// must be set.
+	Output string `yaml:"output"`
+
+	// OutputPath is the file written to when Output is "file".
+	OutputPath string `yaml:"output_path,omitempty"`
+}
+
+type Config struct {
+	// Path to the Google Cloud service account credentials JSON file.
+	CredentialPath string `yaml:"credential_path"`
+
+	// Feed URLs to poll.
+	Feeds []string `yaml:"feeds"`
+
+	// FeedTags maps a feed URL (as it appears in Feeds) to the tags it
+	// should carry, e.g. the OPML folder names it was imported under
+	// (see LoadOPMLFeeds). Feeds with no entry here have no tags.
+	FeedTags map[string][]string `yaml:"feed_tags,omitempty"`
+
+	// Maximum number of items synthesized per feed per run.
+	MaxItemPerFeed int `yaml:"max_item_per_feed"`
+
+	// Number of concurrent synthesis workers.
+	ConcurrentWorkers int `yaml:"concurrent_workers"`
+
+	// Only synthesize items published within the last ItemSince hours.
+	ItemSince float64 `yaml:"item_since"`
+
+	// Whether to request a WaveNet/natural voice instead of the
+	// standard one.
+	UseNaturalVoice bool `yaml:"use_natural_voice"`
+
+	// Playback speed passed to the TTS engine.
+	SpeechSpeed float64 `yaml:"speech_speed"`
+
+	// Audio codec written to disk: "mp3" (default), "opus", "aac",
+	// "flac" or "wav".
+	OutputFormat string `yaml:"output_format"`
+
+	// StorePath is the SQLite database used to track already-synthesized
+	// episodes so unchanged ones are skipped. Defaults to
+	// store.DefaultPath when empty.
+	StorePath string `yaml:"store_path,omitempty"`
+
+	// Server configures the optional podcast-feed HTTP server.
+	Server ServerConfig `yaml:"server"`
+
+	// Log configures the package-level structured logger.
+	Log LogConfig `yaml:"log"`
+}
+
+// NewConfig reads and parses the YAML config file at path.
+func NewConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
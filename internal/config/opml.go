@@ -0,0 +1,165 @@
+package config
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// opmlDocument mirrors the subset of the OPML 2.0 schema rss-to-podcast
+// cares about: a tree of <outline> elements, where an outline with an
+// xmlUrl attribute is a feed subscription and an outline without one is a
+// folder grouping its children.
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// Feed pairs a subscribed feed URL with the names of the OPML folders it
+// was nested under, treating each ancestor folder as a tag.
+type Feed struct {
+	URL  string
+	Tags []string
+}
+
+// LoadOPML reads an OPML subscription list from source, which may be a
+// local file path or an http(s) URL, and returns the feed URLs found in
+// it, for assigning directly to Config.Feeds. Nested folders are
+// flattened for this purpose - their titles group outlines in the
+// source document but aren't retained here - and duplicate xmlUrl
+// entries are deduplicated, keeping the first occurrence. Callers that
+// want the folder names a feed was nested under, treated as tags,
+// should use LoadOPMLFeeds instead.
+func LoadOPML(source string) ([]string, error) {
+	feeds, err := LoadOPMLFeeds(source)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(feeds))
+	for _, f := range feeds {
+		urls = append(urls, f.URL)
+	}
+
+	return urls, nil
+}
+
+// LoadOPMLFeeds reads an OPML subscription list from source, which may
+// be a local file path or an http(s) URL, and returns one Feed per
+// xmlUrl found in it. A feed's Tags are the titles (or, if empty, the
+// text) of every folder it's nested under, outermost first. Duplicate
+// xmlUrl entries are deduplicated, keeping the first occurrence and its
+// tags.
+func LoadOPMLFeeds(source string) ([]Feed, error) {
+	data, err := readOPMLSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing OPML from %s: %w", source, err)
+	}
+
+	seen := make(map[string]bool)
+
+	var feeds []Feed
+
+	var walk func(outlines []opmlOutline, tags []string)
+
+	walk = func(outlines []opmlOutline, tags []string) {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				if !seen[o.XMLURL] {
+					seen[o.XMLURL] = true
+
+					feeds = append(feeds, Feed{URL: o.XMLURL, Tags: tags})
+				}
+
+				continue
+			}
+
+			folder := o.Title
+			if folder == "" {
+				folder = o.Text
+			}
+
+			walk(o.Outlines, append(append([]string{}, tags...), folder))
+		}
+	}
+
+	walk(doc.Body.Outlines, nil)
+
+	return feeds, nil
+}
+
+func readOPMLSource(source string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := http.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("fetching OPML from %s: %w", source, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching OPML from %s: unexpected status %s", source, resp.Status)
+		}
+
+		return io.ReadAll(resp.Body)
+	}
+
+	return os.ReadFile(source)
+}
+
+// ExportOPML writes the given feed URLs to w as a flat OPML 2.0
+// subscription list.
+func ExportOPML(w io.Writer, feeds []string) error {
+	doc := opmlDocument{
+		Body: opmlBody{
+			Outlines: make([]opmlOutline, 0, len(feeds)),
+		},
+	}
+
+	for _, f := range feeds {
+		doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{
+			Text:   f,
+			Title:  f,
+			XMLURL: f,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	type opml struct {
+		XMLName xml.Name `xml:"opml"`
+		Version string   `xml:"version,attr"`
+		Body    opmlBody `xml:"body"`
+	}
+
+	if err := enc.Encode(opml{Version: "2.0", Body: doc.Body}); err != nil {
+		return err
+	}
+
+	_, err := w.Write([]byte("\n"))
+
+	return err
+}
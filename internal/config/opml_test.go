@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoadOPML_MixedFoldersAndDuplicates(t *testing.T) {
+	feeds, err := LoadOPML("testdata/mixed_folders.opml")
+	if err != nil {
+		t.Fatalf("LoadOPML returned error: %v", err)
+	}
+
+	want := []string{
+		"https://news.example.com/rss",
+		"https://another.example.com/feed",
+		"https://tech.example.com/rss",
+		"https://standalone.example.com/feed",
+	}
+
+	if len(feeds) != len(want) {
+		t.Fatalf("expected %d deduplicated feeds, got %d: %v", len(want), len(feeds), feeds)
+	}
+
+	for i, url := range want {
+		if feeds[i] != url {
+			t.Fatalf("feed %d: expected %q, got %q", i, url, feeds[i])
+		}
+	}
+}
+
+func TestLoadOPMLFeeds_TagsFolderNames(t *testing.T) {
+	feeds, err := LoadOPMLFeeds("testdata/mixed_folders.opml")
+	if err != nil {
+		t.Fatalf("LoadOPMLFeeds returned error: %v", err)
+	}
+
+	want := map[string][]string{
+		"https://news.example.com/rss":        {"News"},
+		"https://another.example.com/feed":    {"News"},
+		"https://tech.example.com/rss":        {"Tech"},
+		"https://standalone.example.com/feed": nil,
+	}
+
+	if len(feeds) != len(want) {
+		t.Fatalf("expected %d deduplicated feeds, got %d: %v", len(want), len(feeds), feeds)
+	}
+
+	for _, f := range feeds {
+		tags, ok := want[f.URL]
+		if !ok {
+			t.Fatalf("unexpected feed %q", f.URL)
+		}
+
+		if len(f.Tags) != len(tags) {
+			t.Fatalf("feed %q: expected tags %v, got %v", f.URL, tags, f.Tags)
+		}
+
+		for i := range tags {
+			if f.Tags[i] != tags[i] {
+				t.Fatalf("feed %q: expected tags %v, got %v", f.URL, tags, f.Tags)
+			}
+		}
+	}
+}
+
+func TestExportOPML_IncludesEveryFeed(t *testing.T) {
+	feeds := []string{
+		"https://news.example.com/rss",
+		"https://tech.example.com/rss",
+	}
+
+	var buf bytes.Buffer
+
+	if err := ExportOPML(&buf, feeds); err != nil {
+		t.Fatalf("ExportOPML returned error: %v", err)
+	}
+
+	for _, url := range feeds {
+		if !strings.Contains(buf.String(), url) {
+			t.Fatalf("exported OPML missing feed %q:\n%s", url, buf.String())
+		}
+	}
+}
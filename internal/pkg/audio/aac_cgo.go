@@ -0,0 +1,124 @@
+//go:build cgo
+
+package audio
+
+/*
+#cgo LDFLAGS: -lfdk-aac
+#include <fdk-aac/aacenc_lib.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+)
+
+const aacOutBufSize = 8192
+
+type aacEncoder struct{}
+
+func newAACEncoder() (Encoder, error) {
+	return aacEncoder{}, nil
+}
+
+func (aacEncoder) Encode(pcm []int16, sampleRate int, channels int) ([]byte, error) {
+	var handle C.HANDLE_AACENCODER
+	if C.aacEncOpen(&handle, 0, C.UINT(channels)) != C.AACENC_OK {
+		return nil, fmt.Errorf("aacEncOpen failed")
+	}
+	defer C.aacEncClose(&handle)
+
+	params := []struct {
+		param C.AACENC_PARAM
+		value C.UINT
+	}{
+		{C.AACENC_AOT, 2}, // MPEG-4 AAC-LC
+		{C.AACENC_SAMPLERATE, C.UINT(sampleRate)},
+		{C.AACENC_CHANNELMODE, C.UINT(channels)},
+		{C.AACENC_BITRATE, C.UINT(64000 * channels)},
+		{C.AACENC_TRANSMUX, 2}, // ADTS, so the output is a self-contained stream
+	}
+
+	for _, p := range params {
+		if C.aacEncoder_SetParam(handle, p.param, p.value) != C.AACENC_OK {
+			return nil, fmt.Errorf("aacEncoder_SetParam(%d) failed", p.param)
+		}
+	}
+
+	if C.aacEncEncode(handle, nil, nil, nil, nil) != C.AACENC_OK {
+		return nil, fmt.Errorf("aacEncEncode(init) failed")
+	}
+
+	var info C.AACENC_InfoStruct
+	if C.aacEncInfo(handle, &info) != C.AACENC_OK {
+		return nil, fmt.Errorf("aacEncInfo failed")
+	}
+
+	frameSize := int(info.frameLength) * channels
+
+	var out bytes.Buffer
+
+	outBuf := make([]byte, aacOutBufSize)
+
+	for i := 0; i < len(pcm); i += frameSize {
+		frame := pcm[i:minInt(i+frameSize, len(pcm))]
+		if len(frame) < frameSize {
+			padded := make([]int16, frameSize)
+			copy(padded, frame)
+			frame = padded
+		}
+
+		n, err := encodeAACFrame(handle, frame, outBuf)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Write(outBuf[:n])
+	}
+
+	return out.Bytes(), nil
+}
+
+// encodeAACFrame feeds one frame of interleaved PCM samples to the
+// encoder and returns the number of encoded bytes written into outBuf.
+func encodeAACFrame(handle C.HANDLE_AACENCODER, frame []int16, outBuf []byte) (int, error) {
+	inPtr := unsafe.Pointer(&frame[0])
+	inSize := C.INT(len(frame) * 2)
+	inElSize := C.INT(2)
+	inIdentifier := C.INT(C.IN_AUDIO_DATA)
+
+	inBufDesc := C.AACENC_BufDesc{
+		numBufs:           1,
+		bufs:              &inPtr,
+		bufferIdentifiers: &inIdentifier,
+		bufSizes:          &inSize,
+		bufElSizes:        &inElSize,
+	}
+
+	outPtr := unsafe.Pointer(&outBuf[0])
+	outSize := C.INT(len(outBuf))
+	outElSize := C.INT(1)
+	outIdentifier := C.INT(C.OUT_BITSTREAM_DATA)
+
+	outBufDesc := C.AACENC_BufDesc{
+		numBufs:           1,
+		bufs:              &outPtr,
+		bufferIdentifiers: &outIdentifier,
+		bufSizes:          &outSize,
+		bufElSizes:        &outElSize,
+	}
+
+	inArgs := C.AACENC_InArgs{numInSamples: C.INT(len(frame))}
+
+	var outArgs C.AACENC_OutArgs
+	if C.aacEncEncode(handle, &inBufDesc, &outBufDesc, &inArgs, &outArgs) != C.AACENC_OK {
+		return 0, fmt.Errorf("aacEncEncode failed")
+	}
+
+	return int(outArgs.numOutBytes), nil
+}
+
+func (aacEncoder) Extension() string { return "aac" }
+func (aacEncoder) MIME() string      { return "audio/aac" }
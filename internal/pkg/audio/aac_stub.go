@@ -0,0 +1,9 @@
+//go:build !cgo
+
+package audio
+
+import "fmt"
+
+func newAACEncoder() (Encoder, error) {
+	return nil, fmt.Errorf("aac output requires building with cgo and libfdk-aac")
+}
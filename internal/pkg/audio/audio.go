@@ -0,0 +1,48 @@
+// Package audio provides a pluggable Encoder abstraction so the worker
+// pipeline can emit mp3, Opus, FLAC, AAC or plain WAV without the rest of
+// the codebase caring which codec was picked.
+package audio
+
+import "fmt"
+
+// Encoder turns signed 16-bit PCM samples into an encoded audio byte
+// stream.
+type Encoder interface {
+	// Encode encodes one complete clip. sampleRate and channels describe
+	// the interleaved layout of pcm.
+	Encode(pcm []int16, sampleRate int, channels int) ([]byte, error)
+
+	// Extension is the file extension (without a leading dot) files
+	// produced by this encoder should use.
+	Extension() string
+
+	// MIME is the content type of the encoded output.
+	MIME() string
+}
+
+// NewEncoder returns the Encoder for the given Config.OutputFormat value.
+// An empty format selects mp3, matching the tool's historical default.
+func NewEncoder(format string) (Encoder, error) {
+	switch format {
+	case "", "mp3":
+		return mp3Encoder{}, nil
+	case "opus":
+		return opusEncoder{}, nil
+	case "flac":
+		return flacEncoder{}, nil
+	case "aac":
+		return newAACEncoder()
+	case "wav":
+		return wavEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %q", format)
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,54 @@
+package audio
+
+import (
+	"os"
+
+	goflac "github.com/cocoonlife/goflac"
+)
+
+type flacEncoder struct{}
+
+// Encode shells out to libFLAC via goflac, which only encodes to a file
+// path rather than an io.Writer, so we round-trip through a temp file.
+func (flacEncoder) Encode(pcm []int16, sampleRate int, channels int) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "rss-to-podcast-*.flac")
+	if err != nil {
+		return nil, err
+	}
+
+	path := tmp.Name()
+	tmp.Close()
+
+	defer os.Remove(path)
+
+	enc, err := goflac.NewEncoder(path, channels, 16, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]int32, len(pcm))
+	for i, s := range pcm {
+		samples[i] = int32(s)
+	}
+
+	frame := goflac.Frame{
+		Channels: channels,
+		Depth:    16,
+		Rate:     sampleRate,
+		Samples:  len(pcm) / channels,
+		Buffer:   samples,
+	}
+
+	if err := enc.WriteFrame(frame); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+func (flacEncoder) Extension() string { return "flac" }
+func (flacEncoder) MIME() string      { return "audio/flac" }
@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/viert/lame"
+)
+
+const mp3Bitrate = 96 // kbps, plenty for spoken-word podcasts
+
+type mp3Encoder struct{}
+
+func (mp3Encoder) Encode(pcm []int16, sampleRate int, channels int) ([]byte, error) {
+	var out bytes.Buffer
+
+	enc, err := lame.NewEncoder(&out)
+	if err != nil {
+		return nil, err
+	}
+
+	enc.SetInSamplerate(sampleRate)
+	enc.SetNumChannels(channels)
+	enc.SetBrate(mp3Bitrate)
+	enc.SetMode(lame.JOINT_STEREO)
+
+	if channels == 1 {
+		enc.SetMode(lame.MONO)
+	}
+
+	raw := make([]byte, len(pcm)*bytesPerSample)
+	for i, s := range pcm {
+		binary.LittleEndian.PutUint16(raw[i*bytesPerSample:], uint16(s))
+	}
+
+	if _, err := enc.Write(raw); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func (mp3Encoder) Extension() string { return "mp3" }
+func (mp3Encoder) MIME() string      { return "audio/mpeg" }
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Minimal Ogg container writer, just enough to produce a valid Ogg Opus
+// stream: an ID header page, a comment header page, and one or more
+// audio pages. See RFC 3533 (Ogg) and RFC 7845 (Ogg Opus).
+
+const oggMaxSegmentSize = 255
+
+var oggCRCTable = buildOggCRCTable()
+
+func buildOggCRCTable() [256]uint32 {
+	var t [256]uint32
+
+	for i := range t {
+		r := uint32(i) << 24
+
+		for b := 0; b < 8; b++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+
+		t[i] = r
+	}
+
+	return t
+}
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+
+	for _, b := range data {
+		crc = (crc << 8) ^ oggCRCTable[byte(crc>>24)^b]
+	}
+
+	return crc
+}
+
+// writeOggPage appends a single Ogg page carrying packet to w.
+func writeOggPage(w *bytes.Buffer, packet []byte, headerFlags byte, serial uint32, granule uint64, seq uint32) {
+	var segments []byte
+
+	n := len(packet)
+	for n >= oggMaxSegmentSize {
+		segments = append(segments, oggMaxSegmentSize)
+		n -= oggMaxSegmentSize
+	}
+
+	segments = append(segments, byte(n))
+
+	var page bytes.Buffer
+	page.WriteString("OggS")
+	page.WriteByte(0) // stream structure version
+	page.WriteByte(headerFlags)
+	binary.Write(&page, binary.LittleEndian, granule)
+	binary.Write(&page, binary.LittleEndian, serial)
+	binary.Write(&page, binary.LittleEndian, seq)
+	binary.Write(&page, binary.LittleEndian, uint32(0)) // checksum placeholder
+	page.WriteByte(byte(len(segments)))
+	page.Write(segments)
+	page.Write(packet)
+
+	raw := page.Bytes()
+	binary.LittleEndian.PutUint32(raw[22:26], oggCRC(raw))
+
+	w.Write(raw)
+}
@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+const (
+	// opusBitrate of ~24kbps mono is a big win over mp3 for spoken-word
+	// podcasts on mobile data, at a bitrate still comfortably above
+	// Opus's voice-quality knee.
+	opusBitrate   = 24000
+	opusFrameMS   = 20
+	oggOpusSerial = 1
+)
+
+type opusEncoder struct{}
+
+func (opusEncoder) Encode(pcm []int16, sampleRate int, channels int) ([]byte, error) {
+	enc, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enc.SetBitrate(opusBitrate); err != nil {
+		return nil, err
+	}
+
+	frameSamples := sampleRate / 1000 * opusFrameMS
+	frameLen := frameSamples * channels
+
+	var out bytes.Buffer
+
+	writeOggPage(&out, opusHeadPacket(channels, sampleRate), 0x02, oggOpusSerial, 0, 0)
+	writeOggPage(&out, opusTagsPacket(), 0, oggOpusSerial, 0, 1)
+
+	scratch := make([]byte, 4000)
+	granule := uint64(0)
+	seq := uint32(2)
+
+	for i := 0; i < len(pcm); i += frameLen {
+		frame := pcm[i:minInt(i+frameLen, len(pcm))]
+		if len(frame) < frameLen {
+			padded := make([]int16, frameLen)
+			copy(padded, frame)
+			frame = padded
+		}
+
+		n, err := enc.Encode(frame, scratch)
+		if err != nil {
+			return nil, err
+		}
+
+		// Ogg Opus granule positions always count 48 kHz samples,
+		// whatever the input sample rate.
+		granule += uint64(opusFrameMS * 48)
+
+		flags := byte(0)
+		if i+frameLen >= len(pcm) {
+			flags = 0x04 // end of stream
+		}
+
+		packet := append([]byte(nil), scratch[:n]...)
+		writeOggPage(&out, packet, flags, oggOpusSerial, granule, seq)
+		seq++
+	}
+
+	return out.Bytes(), nil
+}
+
+func (opusEncoder) Extension() string { return "opus" }
+func (opusEncoder) MIME() string      { return "audio/ogg; codecs=opus" }
+
+func opusHeadPacket(channels int, sampleRate int) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("OpusHead")
+	b.WriteByte(1) // version
+	b.WriteByte(byte(channels))
+	binary.Write(&b, binary.LittleEndian, uint16(0))          // pre-skip
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate)) // original sample rate
+	binary.Write(&b, binary.LittleEndian, int16(0))           // output gain
+	b.WriteByte(0)                                            // channel mapping family
+
+	return b.Bytes()
+}
+
+func opusTagsPacket() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("OpusTags")
+
+	vendor := "rss-to-podcast"
+	binary.Write(&b, binary.LittleEndian, uint32(len(vendor)))
+	b.WriteString(vendor)
+	binary.Write(&b, binary.LittleEndian, uint32(0)) // no user comments
+
+	return b.Bytes()
+}
@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	bytesPerSample = 2 // 16-bit PCM
+	wavHeaderSize  = 44
+)
+
+type wavEncoder struct{}
+
+// Encode wraps pcm in a standard 44-byte RIFF/WAVE header, the same
+// layout DecodeWAV reads back from the payloads Google Cloud TTS returns
+// for AudioEncoding_LINEAR16.
+func (wavEncoder) Encode(pcm []int16, sampleRate int, channels int) ([]byte, error) {
+	data := make([]byte, len(pcm)*bytesPerSample)
+	for i, s := range pcm {
+		binary.LittleEndian.PutUint16(data[i*bytesPerSample:], uint16(s))
+	}
+
+	byteRate := sampleRate * channels * bytesPerSample
+	blockAlign := channels * bytesPerSample
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(wavHeaderSize-8+len(data)))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(header[20:22], 1)  // PCM
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(byteRate))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], 16) // bits per sample
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(len(data)))
+
+	return append(header, data...), nil
+}
+
+func (wavEncoder) Extension() string { return "wav" }
+func (wavEncoder) MIME() string      { return "audio/wav" }
+
+// DecodeWAV extracts signed 16-bit PCM samples and format info from a
+// complete RIFF/WAVE byte stream, such as the payload Google Cloud TTS
+// returns when asked for AudioEncoding_LINEAR16.
+func DecodeWAV(b []byte) (pcm []int16, sampleRate int, channels int, err error) {
+	const riffHeaderSize = 12
+	if len(b) < riffHeaderSize || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return nil, 0, 0, fmt.Errorf("not a RIFF/WAVE stream")
+	}
+
+	pos := riffHeaderSize
+
+	var (
+		haveFmt            bool
+		dataStart, dataEnd int
+	)
+
+	for pos+8 <= len(b) {
+		chunkID := string(b[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(b[pos+4 : pos+8]))
+		body := pos + 8
+
+		if body+chunkSize > len(b) {
+			break
+		}
+
+		switch chunkID {
+		case "fmt ":
+			channels = int(binary.LittleEndian.Uint16(b[body+2 : body+4]))
+			sampleRate = int(binary.LittleEndian.Uint32(b[body+4 : body+8]))
+			haveFmt = true
+		case "data":
+			dataStart, dataEnd = body, body+chunkSize
+		}
+
+		pos = body + chunkSize
+		if chunkSize%2 == 1 {
+			pos++
+		}
+	}
+
+	if !haveFmt || dataEnd == 0 {
+		return nil, 0, 0, fmt.Errorf("missing fmt or data chunk")
+	}
+
+	raw := b[dataStart:dataEnd]
+	pcm = make([]int16, len(raw)/bytesPerSample)
+
+	for i := range pcm {
+		pcm[i] = int16(binary.LittleEndian.Uint16(raw[i*bytesPerSample:]))
+	}
+
+	return pcm, sampleRate, channels, nil
+}
@@ -0,0 +1,157 @@
+// Package chunk splits article text into pieces small enough to satisfy
+// Google Cloud Text-to-Speech's per-request SSML size limit.
+package chunk
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxBytes is the default per-chunk limit, counted on the text
+// *after* SSML escaping. Google rejects SynthesizeSpeech requests whose
+// SSML payload exceeds ~5000 bytes, so we stay comfortably under that.
+const DefaultMaxBytes = 4800
+
+var (
+	paragraphBoundary = regexp.MustCompile(`\n\s*\n`)
+	sentenceBoundary  = regexp.MustCompile(`([.!?。！？]+\s*)`)
+	wordBoundary      = regexp.MustCompile(`(\s+)`)
+)
+
+var ssmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// EscapeSSML escapes the characters that are special inside an SSML
+// document so the result is safe to embed in a <speak> payload.
+func EscapeSSML(s string) string {
+	return ssmlEscaper.Replace(s)
+}
+
+// SplitForSSML splits text into chunks whose SSML-escaped byte length is
+// at most maxBytes. It prefers to cut at paragraph, then sentence, then
+// word boundaries, and only falls back to splitting mid-word (rune by
+// rune) for unbroken runs of text such as Chinese prose with no ASCII
+// whitespace. maxBytes <= 0 selects DefaultMaxBytes.
+func SplitForSSML(text string, maxBytes int) []string {
+	return SplitText(text, maxBytes, EscapeSSML)
+}
+
+// SplitText splits text into chunks whose length, after transform is
+// applied, is at most maxBytes. transform lets callers account for
+// escaping (SSML) or pass strings straight through (plain text engines
+// such as the offline Sherpa backend). maxBytes <= 0 selects
+// DefaultMaxBytes.
+func SplitText(text string, maxBytes int, transform func(string) string) []string {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	if transform == nil {
+		transform = func(s string) string { return s }
+	}
+
+	units := splitKeepDelim(text, paragraphBoundary)
+
+	return pack(units, maxBytes, transform)
+}
+
+// pack greedily accumulates units into chunks no larger than maxBytes
+// (once escaped). Units that alone exceed the limit are recursively
+// split at a finer granularity.
+func pack(units []string, maxBytes int, transform func(string) string) []string {
+	var chunks []string
+
+	var cur strings.Builder
+
+	flush := func() {
+		if trimmed := strings.TrimSpace(cur.String()); trimmed != "" {
+			chunks = append(chunks, trimmed)
+		}
+
+		cur.Reset()
+	}
+
+	for _, u := range units {
+		if u == "" {
+			continue
+		}
+
+		if len(transform(u)) > maxBytes {
+			flush()
+
+			// A single rune can't be split any finer - splitFiner would
+			// just hand it straight back, recursing into pack forever.
+			// Emit it as an over-limit chunk instead; there's nothing
+			// smaller we can do with it.
+			if len([]rune(u)) <= 1 {
+				chunks = append(chunks, u)
+
+				continue
+			}
+
+			chunks = append(chunks, pack(splitFiner(u), maxBytes, transform)...)
+
+			continue
+		}
+
+		if cur.Len() > 0 && len(transform(cur.String()+u)) > maxBytes {
+			flush()
+		}
+
+		cur.WriteString(u)
+	}
+
+	flush()
+
+	return chunks
+}
+
+// splitFiner breaks a single oversized unit into the next-finer
+// granularity: sentences, then words, then individual runes.
+func splitFiner(unit string) []string {
+	if sentences := splitKeepDelim(unit, sentenceBoundary); len(sentences) > 1 {
+		return sentences
+	}
+
+	if words := splitKeepDelim(unit, wordBoundary); len(words) > 1 {
+		return words
+	}
+
+	runes := []rune(unit)
+	out := make([]string, len(runes))
+
+	for i, r := range runes {
+		out[i] = string(r)
+	}
+
+	return out
+}
+
+// splitKeepDelim splits s on re, keeping the delimiter attached to the
+// preceding unit so that joining the returned slice reproduces s exactly.
+func splitKeepDelim(s string, re *regexp.Regexp) []string {
+	locs := re.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return []string{s}
+	}
+
+	var out []string
+
+	prev := 0
+
+	for _, loc := range locs {
+		out = append(out, s[prev:loc[1]])
+		prev = loc[1]
+	}
+
+	if prev < len(s) {
+		out = append(out, s[prev:])
+	}
+
+	return out
+}
@@ -0,0 +1,92 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitForSSML_ChineseWithNoASCIIWhitespace(t *testing.T) {
+	// No ASCII whitespace and no sentence punctuation at all, so the
+	// splitter has to fall back all the way to rune-by-rune packing.
+	text := strings.Repeat("字", 6000)
+
+	chunks := SplitForSSML(text, DefaultMaxBytes)
+
+	if len(chunks) < 2 {
+		t.Fatalf("expected text to be split into multiple chunks, got %d", len(chunks))
+	}
+
+	var rebuilt strings.Builder
+	for _, c := range chunks {
+		if got := len(EscapeSSML(c)); got > DefaultMaxBytes {
+			t.Fatalf("chunk exceeds DefaultMaxBytes: got %d bytes", got)
+		}
+
+		rebuilt.WriteString(c)
+	}
+
+	if rebuilt.String() != text {
+		t.Fatalf("splitting lost or reordered content")
+	}
+}
+
+func TestSplitForSSML_ExactlyOneByteOverLimit(t *testing.T) {
+	limit := 20
+	text := strings.Repeat("a", limit+1)
+
+	chunks := SplitForSSML(text, limit)
+
+	if len(chunks) != 2 {
+		t.Fatalf("expected exactly 2 chunks for a one-byte overflow, got %d: %v", len(chunks), chunks)
+	}
+
+	if chunks[0]+chunks[1] != text {
+		t.Fatalf("rejoined chunks %q+%q != original %q", chunks[0], chunks[1], text)
+	}
+
+	for _, c := range chunks {
+		if len(EscapeSSML(c)) > limit {
+			t.Fatalf("chunk %q exceeds limit %d once escaped", c, limit)
+		}
+	}
+}
+
+func TestSplitForSSML_SingleRuneOverLimitDoesNotRecurseForever(t *testing.T) {
+	// A single rune whose SSML-escaped form alone exceeds maxBytes can't
+	// be split any finer; SplitForSSML must still return it rather than
+	// recursing into pack forever.
+	chunks := SplitForSSML("😀", 3)
+
+	if len(chunks) != 1 || chunks[0] != "😀" {
+		t.Fatalf("expected the oversized rune back unchanged as a single chunk, got %v", chunks)
+	}
+}
+
+func TestSplitForSSML_MidSSMLEscapeBoundary(t *testing.T) {
+	// "&" escapes to "&amp;" (5 bytes). Pick a limit that would fall in
+	// the middle of that escape sequence (right after "&am") if splitting
+	// were done on the escaped form rather than per source rune.
+	text := "one two three & four five"
+	limit := len(EscapeSSML("one two three ")) + len("&am")
+
+	chunks := SplitForSSML(text, limit)
+
+	for _, c := range chunks {
+		escaped := EscapeSSML(c)
+
+		if len(escaped) > limit {
+			t.Fatalf("chunk %q escapes to %q (%d bytes), exceeding limit %d", c, escaped, len(escaped), limit)
+		}
+
+		if strings.Contains(escaped, "&am") && !strings.Contains(escaped, "&amp;") {
+			t.Fatalf("chunk %q contains a truncated SSML escape sequence", escaped)
+		}
+	}
+
+	joined := strings.Join(chunks, " ")
+	for _, word := range strings.Fields(text) {
+		if !strings.Contains(joined, word) {
+			t.Fatalf("chunk set %v lost word %q from original text", chunks, word)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package logging builds the package-level *slog.Logger used throughout
+// rss-to-podcast from the user's LogConfig.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/KevinSJ/rss-to-podcast/internal/config"
+)
+
+// New builds a *slog.Logger from cfg. An empty LogConfig produces a text
+// logger at info level writing to stderr, matching the program's previous
+// log.Printf-based behavior.
+func New(cfg config.LogConfig) (*slog.Logger, error) {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := resolveOutput(cfg.Output, cfg.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(output, opts)
+	} else {
+		handler = slog.NewTextHandler(output, opts)
+	}
+
+	return slog.New(handler), nil
+}
+
+func parseLevel(level string) (slog.Level, error) {
+	switch level {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+func resolveOutput(output string, path string) (*os.File, error) {
+	switch output {
+	case "", "stderr":
+		return os.Stderr, nil
+	case "file":
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening log output file %s: %w", path, err)
+		}
+
+		return f, nil
+	default:
+		return nil, fmt.Errorf("unknown log output %q", output)
+	}
+}
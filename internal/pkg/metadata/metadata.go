@@ -0,0 +1,62 @@
+// Package metadata stores the per-episode JSON sidecar the worker writes
+// alongside each generated audio file, so the podcast feed server can
+// recover the item's title, GUID and publish time without re-parsing ID3
+// tags or trusting file mtimes, which a careless `cp`/backup can change.
+//
+// It's a standalone package, rather than living in internal/pkg/worker or
+// internal/pkg/podcast, because both of those packages need it: worker
+// writes sidecars, podcast reads them back to render the feed.
+package metadata
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EpisodeMeta is the per-episode metadata sidecar the worker writes
+// alongside each generated audio file.
+type EpisodeMeta struct {
+	Title   string    `json:"title"`
+	GUID    string    `json:"guid"`
+	FeedURL string    `json:"feed_url,omitempty"`
+	PubDate time.Time `json:"pub_date"`
+
+	// Tags carries the feed's tags (e.g. the OPML folder names it was
+	// imported under) through to the rendered podcast feed.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// SidecarPath returns the path of the metadata sidecar for the audio file
+// at audioPath, whether or not it exists yet.
+func SidecarPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".meta.json"
+}
+
+// WriteSidecar writes meta as a JSON sidecar next to the audio file at
+// audioPath.
+func WriteSidecar(audioPath string, meta EpisodeMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(SidecarPath(audioPath), data, 0o644)
+}
+
+// ReadSidecar reads the metadata sidecar for audioPath, if one exists.
+func ReadSidecar(audioPath string) (*EpisodeMeta, bool) {
+	data, err := os.ReadFile(SidecarPath(audioPath))
+	if err != nil {
+		return nil, false
+	}
+
+	var meta EpisodeMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, false
+	}
+
+	return &meta, true
+}
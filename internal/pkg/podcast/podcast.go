@@ -0,0 +1,329 @@
+// Package podcast renders already-synthesized episodes as an itunes-
+// compatible podcast RSS 2.0 feed and serves both the feed XML and the
+// underlying audio files over HTTP.
+package podcast
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/audio"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/metadata"
+	"github.com/bogem/id3v2"
+	"github.com/tcolgate/mp3"
+)
+
+// audioExtensions are the file extensions ScanFeedDir considers
+// synthesized episodes, matching every format audio.NewEncoder can
+// produce.
+var audioExtensions = map[string]bool{
+	".mp3":  true,
+	".opus": true,
+	".flac": true,
+	".aac":  true,
+	".wav":  true,
+}
+
+// Episode describes one synthesized episode ready to be published as a
+// feed item.
+type Episode struct {
+	Title    string
+	GUID     string
+	FileName string
+	Size     int64
+	Duration time.Duration
+	PubDate  time.Time
+	MIME     string
+	Tags     []string
+}
+
+// ScanFeedDir reads every synthesized episode in dir, in any format
+// audio.NewEncoder can produce, and returns one Episode per file, reading
+// the title from its ID3v2 tag for mp3s (falling back to the filename)
+// and its publish date from the file's mtime, which the worker already
+// sets via os.Chtimes to the item's original publish/update time. When
+// the file has a metadata sidecar, the sidecar's title, GUID, publish
+// date and tags are used instead.
+func ScanFeedDir(dir string) ([]Episode, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make([]Episode, 0, len(entries))
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !audioExtensions[ext] {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		duration, err := episodeDuration(path, ext)
+		if err != nil {
+			return nil, fmt.Errorf("reading duration of %s: %w", path, err)
+		}
+
+		title, guid, pubDate := entry.Name(), "", info.ModTime()
+
+		var tags []string
+
+		if ext == ".mp3" {
+			title = mp3Title(path, title)
+		}
+
+		if meta, ok := metadata.ReadSidecar(path); ok {
+			title, guid, pubDate, tags = meta.Title, meta.GUID, meta.PubDate, meta.Tags
+		}
+
+		episodes = append(episodes, Episode{
+			Title:    title,
+			GUID:     guid,
+			FileName: entry.Name(),
+			Size:     info.Size(),
+			Duration: duration,
+			PubDate:  pubDate,
+			MIME:     mimeForExtension(ext),
+			Tags:     tags,
+		})
+	}
+
+	return episodes, nil
+}
+
+func mp3Title(path string, fallback string) string {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return fallback
+	}
+	defer tag.Close()
+
+	if title := tag.Title(); title != "" {
+		return title
+	}
+
+	return fallback
+}
+
+// episodeDuration measures the playback length of the episode at path.
+// Only formats this package knows how to decode (mp3 via a frame-level
+// decoder, wav via audio.DecodeWAV) report a real duration; other
+// formats report zero, which itunes:duration tolerates as "unknown".
+func episodeDuration(path string, ext string) (time.Duration, error) {
+	switch ext {
+	case ".mp3":
+		return mp3Duration(path)
+	case ".wav":
+		return wavDuration(path)
+	default:
+		return 0, nil
+	}
+}
+
+func mp3Duration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	decoder := mp3.NewDecoder(f)
+
+	var (
+		frame   mp3.Frame
+		skipped int
+		total   time.Duration
+	)
+
+	for {
+		if err := decoder.Decode(&frame, &skipped); err != nil {
+			break
+		}
+
+		total += frame.Duration()
+	}
+
+	return total, nil
+}
+
+func wavDuration(path string) (time.Duration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pcm, sampleRate, channels, err := audio.DecodeWAV(data)
+	if err != nil {
+		return 0, err
+	}
+
+	if sampleRate == 0 || channels == 0 {
+		return 0, nil
+	}
+
+	frames := len(pcm) / channels
+
+	return time.Duration(frames) * time.Second / time.Duration(sampleRate), nil
+}
+
+// mimeForExtension returns the content type of a file with the given
+// extension, as produced by the audio.Encoder for that format.
+func mimeForExtension(ext string) string {
+	format := strings.TrimPrefix(ext, ".")
+
+	encoder, err := audio.NewEncoder(format)
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	return encoder.MIME()
+}
+
+// Channel XML shape below mirrors the itunes podcast RSS spec:
+// https://podcasters.apple.com/support/823-podcast-requirements
+
+type rssFeed struct {
+	XMLName   xml.Name `xml:"rss"`
+	Version   string   `xml:"version,attr"`
+	ItunesXML string   `xml:"xmlns:itunes,attr"`
+	Channel   channel  `xml:"channel"`
+}
+
+type channel struct {
+	Title          string          `xml:"title"`
+	Link           string          `xml:"link"`
+	Description    string          `xml:"description"`
+	ItunesAuthor   string          `xml:"itunes:author,omitempty"`
+	ItunesImage    *itunesImage    `xml:"itunes:image,omitempty"`
+	ItunesCategory *itunesCategory `xml:"itunes:category,omitempty"`
+	Items          []item          `xml:"item"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type itunesCategory struct {
+	Text string `xml:"text,attr"`
+}
+
+type item struct {
+	Title          string    `xml:"title"`
+	GUID           guid      `xml:"guid"`
+	PubDate        string    `xml:"pubDate"`
+	Enclosure      enclosure `xml:"enclosure"`
+	ItunesDuration string    `xml:"itunes:duration"`
+	Categories     []string  `xml:"category"`
+}
+
+type guid struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// ChannelMeta carries the channel-level fields that can't be derived
+// from the episode files themselves.
+type ChannelMeta struct {
+	Title       string
+	Description string
+	Author      string
+	ImageURL    string
+	Category    string
+}
+
+// BuildFeedXML renders episodes into a complete itunes-namespaced RSS 2.0
+// document. baseURL is used to build the feed's own link and each
+// episode's enclosure/audio URL as baseURL + "/audio/" + slug + "/" + filename.
+// Each episode's Tags are rendered as plain RSS <category> elements.
+func BuildFeedXML(baseURL string, slug string, meta ChannelMeta, episodes []Episode) ([]byte, error) {
+	feedURL := fmt.Sprintf("%s/feeds/%s.xml", baseURL, slug)
+
+	ch := channel{
+		Title:        meta.Title,
+		Link:         feedURL,
+		Description:  meta.Description,
+		ItunesAuthor: meta.Author,
+	}
+
+	if meta.ImageURL != "" {
+		ch.ItunesImage = &itunesImage{Href: meta.ImageURL}
+	}
+
+	if meta.Category != "" {
+		ch.ItunesCategory = &itunesCategory{Text: meta.Category}
+	}
+
+	for _, ep := range episodes {
+		audioURL := fmt.Sprintf("%s/audio/%s/%s", baseURL, slug, ep.FileName)
+
+		guidValue := ep.GUID
+		if guidValue == "" {
+			guidValue = audioURL
+		}
+
+		ch.Items = append(ch.Items, item{
+			Title: ep.Title,
+			GUID: guid{
+				IsPermaLink: "false",
+				Value:       guidValue,
+			},
+			PubDate: ep.PubDate.Format(time.RFC1123Z),
+			Enclosure: enclosure{
+				URL:    audioURL,
+				Length: ep.Size,
+				Type:   mimeOrDefault(ep.MIME),
+			},
+			ItunesDuration: formatDuration(ep.Duration),
+			Categories:     ep.Tags,
+		})
+	}
+
+	feed := rssFeed{
+		Version:   "2.0",
+		ItunesXML: "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel:   ch,
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+// mimeOrDefault falls back to "audio/mpeg" for episodes built without a
+// MIME set (e.g. by callers outside ScanFeedDir).
+func mimeOrDefault(mime string) string {
+	if mime == "" {
+		return "audio/mpeg"
+	}
+
+	return mime
+}
+
+// formatDuration renders d as itunes:duration's HH:MM:SS form.
+func formatDuration(d time.Duration) string {
+	total := int(d.Round(time.Second).Seconds())
+	hours := total / 3600
+	minutes := (total % 3600) / 60
+	seconds := total % 60
+
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
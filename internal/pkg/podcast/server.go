@@ -0,0 +1,103 @@
+package podcast
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KevinSJ/rss-to-podcast/internal/config"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/worker"
+	"golang.org/x/time/rate"
+)
+
+// Serve starts the podcast HTTP server configured by cfg.Server. It
+// blocks until the server stops.
+//
+// Each subdirectory of cfg.Server.FeedsDir is published as its own
+// channel: /feeds/{slug}.xml for the RSS feed and /audio/{slug}/{file}
+// for the audio files themselves, where {slug} is the subdirectory name.
+// If group is non-nil and cfg.Server.TTS.RequestsPerSecond is positive,
+// POST /tts is also registered to synthesize an arbitrary URL on demand
+// through the same worker pool.
+func Serve(cfg *config.Config, group *worker.WorkerGroup, logger *slog.Logger) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feeds/", handleFeed(cfg))
+	mux.HandleFunc("/audio/", handleAudio(cfg))
+
+	if group != nil && cfg.Server.TTS.RequestsPerSecond > 0 {
+		limiter := rate.NewLimiter(rate.Limit(cfg.Server.TTS.RequestsPerSecond), cfg.Server.TTS.Burst)
+		quota := newCallerQuota(cfg.Server.TTS.QuotaPerCallerPerDay, 24*time.Hour)
+		mux.HandleFunc("/tts", handleTTS(cfg, group, limiter, quota, logger))
+	}
+
+	var handler http.Handler = mux
+	if cfg.Server.BasicAuthUser != "" && cfg.Server.BasicAuthPass != "" {
+		handler = basicAuth(cfg.Server.BasicAuthUser, cfg.Server.BasicAuthPass, handler)
+	}
+
+	logger.Info("podcast server listening", "addr", cfg.Server.Listen)
+
+	return http.ListenAndServe(cfg.Server.Listen, handler)
+}
+
+func handleFeed(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/feeds/")
+
+		slug := strings.TrimSuffix(name, ".xml")
+		if slug == "" || slug == name {
+			http.NotFound(w, r)
+			return
+		}
+
+		dir := filepath.Join(cfg.Server.FeedsDir, slug)
+
+		episodes, err := ScanFeedDir(dir)
+		if err != nil {
+			http.Error(w, "feed not found", http.StatusNotFound)
+			return
+		}
+
+		body, err := BuildFeedXML(cfg.Server.BaseURL, slug, ChannelMeta{Title: slug}, episodes)
+		if err != nil {
+			http.Error(w, "failed to render feed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		w.Write(body)
+	}
+}
+
+func handleAudio(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/audio/")
+
+		slug, file, ok := strings.Cut(rest, "/")
+		if !ok || slug == "" || file == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.ServeFile(w, r, filepath.Join(cfg.Server.FeedsDir, slug, file))
+	}
+}
+
+func basicAuth(user string, pass string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(gotUser), []byte(user)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(gotPass), []byte(pass)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rss-to-podcast"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
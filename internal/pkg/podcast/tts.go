@@ -0,0 +1,336 @@
+package podcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevinSJ/rss-to-podcast/internal/config"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/worker"
+	readability "github.com/go-shiori/go-readability"
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/time/rate"
+)
+
+// ttsSynthesisTimeout bounds how long handleTTS waits for the worker
+// pool to produce the output file before giving up on a synchronous
+// response.
+const ttsSynthesisTimeout = 2 * time.Minute
+
+const ttsPollInterval = 500 * time.Millisecond
+
+type ttsRequest struct {
+	URL      string `json:"url"`
+	Language string `json:"language"`
+	Voice    string `json:"voice"`
+}
+
+// callerQuota tracks how many on-demand requests each caller has made in
+// its current window, which starts at the caller's first request and
+// resets once it has passed, to protect the paid Google TTS backend from
+// a single abusive caller.
+type callerQuota struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	used   map[string]*quotaWindow
+}
+
+type quotaWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+func newCallerQuota(limit int, window time.Duration) *callerQuota {
+	return &callerQuota{limit: limit, window: window, used: make(map[string]*quotaWindow)}
+}
+
+// Allow reports whether caller may make another request, consuming one
+// unit of quota if so. A limit of zero means unlimited.
+func (q *callerQuota) Allow(caller string, now time.Time) bool {
+	if q.limit <= 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w, ok := q.used[caller]
+	if !ok || now.After(w.resetAt) {
+		w = &quotaWindow{resetAt: now.Add(q.window)}
+		q.used[caller] = w
+	}
+
+	if w.count >= q.limit {
+		return false
+	}
+
+	w.count++
+
+	return true
+}
+
+// handleTTS serves POST /tts: it extracts article text from the given
+// URL, submits it to the worker pool as a synthetic feed item, and
+// redirects to the resulting audio once synthesis finishes.
+func handleTTS(cfg *config.Config, group *worker.WorkerGroup, limiter *rate.Limiter, quota *callerQuota, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		caller := callerIP(r)
+		if !limiter.Allow() {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		if !quota.Allow(caller, time.Now()) {
+			http.Error(w, "daily quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		var req ttsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := validateFetchURL(req.URL); err != nil {
+			logger.Warn("rejecting tts url", "url", req.URL, "error", err)
+			http.Error(w, "url not allowed", http.StatusBadRequest)
+
+			return
+		}
+
+		resp, err := fetchArticlePage(req.URL, 30*time.Second)
+		if err != nil {
+			logger.Error("fetching article failed", "url", req.URL, "error", err)
+			http.Error(w, "failed to fetch article", http.StatusBadGateway)
+
+			return
+		}
+		defer resp.Body.Close()
+
+		parsedURL, err := url.Parse(req.URL)
+		if err != nil {
+			logger.Error("parsing article url failed", "url", req.URL, "error", err)
+			http.Error(w, "failed to fetch article", http.StatusBadGateway)
+
+			return
+		}
+
+		article, err := readability.FromReader(resp.Body, parsedURL)
+		if err != nil {
+			logger.Error("parsing article failed", "url", req.URL, "error", err)
+			http.Error(w, "failed to fetch article", http.StatusBadGateway)
+
+			return
+		}
+
+		language := req.Language
+		if language == "" {
+			language = "en-US"
+		}
+
+		now := time.Now()
+		item := &gofeed.Item{
+			Title:           article.Title,
+			Content:         article.TextContent,
+			GUID:            req.URL,
+			PublishedParsed: &now,
+		}
+
+		outputDir := filepath.Join(cfg.Server.FeedsDir, cfg.Server.TTS.OutputDir)
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			logger.Error("creating on-demand output dir failed", "dir", outputDir, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+
+			return
+		}
+
+		group.Submit(&worker.WorkerRequest{
+			Item:         item,
+			Directory:    outputDir,
+			LanguageCode: language,
+			// The on-demand endpoint only exposes a single toggle
+			// between the standard and a WaveNet/natural voice; any
+			// explicit voice name just opts into the natural one.
+			UseNaturalVoice: req.Voice != "",
+			SpeechSpeed:     cfg.SpeechSpeed,
+		})
+
+		fileName := group.OutputFileName(item)
+
+		if waitForFile(filepath.Join(outputDir, fileName), ttsSynthesisTimeout) {
+			http.Redirect(w, r, strings.Join([]string{"/audio", cfg.Server.TTS.OutputDir, fileName}, "/"), http.StatusFound)
+			return
+		}
+
+		http.Error(w, "synthesis timed out", http.StatusGatewayTimeout)
+	}
+}
+
+// callerIP returns r's client address without the ephemeral port, so the
+// per-caller quota actually keys on the same value across requests from
+// the same client.
+func callerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// validateFetchURL rejects rawURL unless it's a plain http(s) URL that
+// resolves only to public addresses. It's a fast, loud pre-check so
+// handleTTS can reject an obviously disallowed URL before even trying
+// to fetch it; the fetch itself (fetchArticlePage) re-validates every
+// address it actually dials, since this check alone can't catch a
+// redirect or a DNS answer that changes between here and the fetch.
+func validateFetchURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parsing url: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("missing host")
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("resolving host %q: %w", host, err)
+	}
+
+	for _, ip := range ips {
+		if isDisallowedIP(ip) {
+			return fmt.Errorf("host %q resolves to disallowed address %s", host, ip)
+		}
+	}
+
+	return nil
+}
+
+// isDisallowedIP reports whether ip is loopback, private, link-local,
+// unspecified or multicast, and therefore off-limits for server-side
+// fetches.
+func isDisallowedIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() ||
+		ip.IsMulticast()
+}
+
+// fetchArticlePage fetches rawURL for readability.FromReader, using a
+// client that resolves and validates every address it actually dials -
+// including the target of every redirect hop - instead of trusting a
+// one-off check against the caller-supplied hostname. That's what
+// closes off both a malicious redirect to a disallowed address and
+// classic DNS rebinding (a name resolving to a public address at check
+// time and a private one at fetch time): the address checked here is
+// the exact one connected to, every time.
+func fetchArticlePage(rawURL string, timeout time.Duration) (*http.Response, error) {
+	resp, err := safeHTTPClient(timeout).Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "text/html") {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("fetching %s: not an HTML document", rawURL)
+	}
+
+	return resp, nil
+}
+
+// safeHTTPClient returns an http.Client whose every dial - including
+// across redirects - is resolved and checked by safeDialIP rather than
+// left to the standard library's own hostname resolution.
+func safeHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				ip, err := safeDialIP(addr)
+				if err != nil {
+					return nil, err
+				}
+
+				return dialer.DialContext(ctx, network, ip)
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return validateFetchURL(req.URL.String())
+		},
+	}
+}
+
+// safeDialIP resolves the host in addr (host:port) and returns
+// host:port rewritten to a single allowed, literal IP address - so the
+// caller dials the exact address that was checked, instead of handing
+// the hostname to the transport and risking a second, unvalidated
+// lookup.
+func safeDialIP(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("parsing dial address %q: %w", addr, err)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isDisallowedIP(ip) {
+			return "", fmt.Errorf("address %s is disallowed", ip)
+		}
+
+		return addr, nil
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", fmt.Errorf("resolving host %q: %w", host, err)
+	}
+
+	for _, ip := range ips {
+		if !isDisallowedIP(ip) {
+			return net.JoinHostPort(ip.String(), port), nil
+		}
+	}
+
+	return "", fmt.Errorf("host %q has no allowed address", host)
+}
+
+// waitForFile polls for path to exist, up to timeout.
+func waitForFile(path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+
+		time.Sleep(ttsPollInterval)
+	}
+
+	return false
+}
@@ -0,0 +1,134 @@
+package podcast
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCallerQuota_AllowsUpToLimitThenBlocks(t *testing.T) {
+	q := newCallerQuota(2, time.Hour)
+	now := time.Now()
+
+	if !q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected 1st request to be allowed")
+	}
+
+	if !q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected 2nd request to be allowed")
+	}
+
+	if q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected 3rd request to exceed the limit")
+	}
+}
+
+func TestCallerQuota_TracksCallersIndependently(t *testing.T) {
+	q := newCallerQuota(1, time.Hour)
+	now := time.Now()
+
+	if !q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected caller A's 1st request to be allowed")
+	}
+
+	if !q.Allow("5.6.7.8", now) {
+		t.Fatalf("expected caller B's 1st request to be allowed despite caller A being at quota")
+	}
+
+	if q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected caller A's 2nd request to exceed the limit")
+	}
+}
+
+func TestCallerQuota_ResetsAfterWindow(t *testing.T) {
+	q := newCallerQuota(1, time.Hour)
+	now := time.Now()
+
+	if !q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected 1st request to be allowed")
+	}
+
+	if q.Allow("1.2.3.4", now) {
+		t.Fatalf("expected 2nd request within the window to exceed the limit")
+	}
+
+	if !q.Allow("1.2.3.4", now.Add(time.Hour+time.Minute)) {
+		t.Fatalf("expected a request past the window to be allowed again")
+	}
+}
+
+func TestCallerQuota_ZeroLimitIsUnlimited(t *testing.T) {
+	q := newCallerQuota(0, time.Hour)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		if !q.Allow("1.2.3.4", now) {
+			t.Fatalf("expected every request to be allowed with a zero limit, failed on request %d", i)
+		}
+	}
+}
+
+func TestCallerIP_StripsEphemeralPort(t *testing.T) {
+	r := &http.Request{RemoteAddr: "203.0.113.7:54321"}
+
+	if got := callerIP(r); got != "203.0.113.7" {
+		t.Fatalf("expected port to be stripped, got %q", got)
+	}
+}
+
+func TestCallerIP_FallsBackToRawRemoteAddrWithoutPort(t *testing.T) {
+	r := &http.Request{RemoteAddr: "not-a-host-port"}
+
+	if got := callerIP(r); got != "not-a-host-port" {
+		t.Fatalf("expected the raw RemoteAddr back when it has no port, got %q", got)
+	}
+}
+
+func TestValidateFetchURL_RejectsNonHTTPScheme(t *testing.T) {
+	if err := validateFetchURL("ftp://example.com/file"); err == nil {
+		t.Fatalf("expected a non-http(s) scheme to be rejected")
+	}
+}
+
+func TestValidateFetchURL_RejectsLoopbackAndLinkLocal(t *testing.T) {
+	for _, rawURL := range []string{
+		"http://127.0.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+	} {
+		if err := validateFetchURL(rawURL); err == nil {
+			t.Fatalf("expected %q to be rejected", rawURL)
+		}
+	}
+}
+
+func TestValidateFetchURL_AllowsPublicHTTPURL(t *testing.T) {
+	if err := validateFetchURL("http://93.184.216.34/"); err != nil {
+		t.Fatalf("expected a public IP literal to be allowed, got error: %v", err)
+	}
+}
+
+func TestIsDisallowedIP(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":       true,
+		"169.254.169.254": true,
+		"10.0.0.1":        true,
+		"192.168.1.1":     true,
+		"0.0.0.0":         true,
+		"224.0.0.1":       true,
+		"93.184.216.34":   false,
+		"8.8.8.8":         false,
+	}
+
+	for addr, want := range cases {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			t.Fatalf("net.ParseIP(%q) returned nil", addr)
+		}
+
+		if got := isDisallowedIP(ip); got != want {
+			t.Fatalf("isDisallowedIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package rss turns a parsed feed item into the SynthesizeSpeech requests
+// needed to voice it, splitting long articles into multiple requests so
+// that none of them exceed Google Cloud Text-to-Speech's SSML size limit.
+package rss
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/chunk"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/tool"
+	"github.com/mmcdole/gofeed"
+)
+
+// NaturalVoiceSuffix is appended to the base language code to select a
+// WaveNet voice instead of the standard (robotic) one.
+const NaturalVoiceSuffix = "-Wavenet-A"
+
+// ArticleContent builds the plain-text script synthesized for item: its
+// title followed by its stripped content, falling back to its
+// description when it has no content. Exported so callers can hash it
+// for change detection without duplicating this logic.
+func ArticleContent(item *gofeed.Item) string {
+	content := item.Title + "\n\n"
+
+	if len(item.Content) > 0 {
+		content += tool.StripHtmlTags(item.Content)
+	} else if len(item.Description) > 0 {
+		content += tool.StripHtmlTags(item.Description)
+	}
+
+	return content
+}
+
+// GetSynthesizeSpeechRequests builds one SynthesizeSpeechRequest per
+// chunk.DefaultMaxBytes-sized piece of the item's content, so articles of
+// any length can be synthesized without Google rejecting the request for
+// exceeding its SSML payload limit.
+//
+// Requests always ask Google for raw LINEAR16 audio rather than a
+// specific codec: the worker re-encodes the decoded PCM through the
+// audio.Encoder selected by Config.OutputFormat, so every TTS backend
+// (Google or offline) produces output through the same codec pipeline.
+func GetSynthesizeSpeechRequests(
+	item *gofeed.Item,
+	languageCode string,
+	useNaturalVoice bool,
+	speed float64,
+) []*texttospeechpb.SynthesizeSpeechRequest {
+	content := ArticleContent(item)
+
+	voiceName := ""
+	if useNaturalVoice {
+		voiceName = languageCode + NaturalVoiceSuffix
+	}
+
+	chunks := chunk.SplitForSSML(content, chunk.DefaultMaxBytes)
+	requests := make([]*texttospeechpb.SynthesizeSpeechRequest, 0, len(chunks))
+
+	for _, c := range chunks {
+		requests = append(requests, &texttospeechpb.SynthesizeSpeechRequest{
+			Input: &texttospeechpb.SynthesisInput{
+				InputSource: &texttospeechpb.SynthesisInput_Ssml{
+					Ssml: fmt.Sprintf("<speak>%s</speak>", chunk.EscapeSSML(c)),
+				},
+			},
+			Voice: &texttospeechpb.VoiceSelectionParams{
+				LanguageCode: languageCode,
+				Name:         voiceName,
+			},
+			AudioConfig: &texttospeechpb.AudioConfig{
+				AudioEncoding: texttospeechpb.AudioEncoding_LINEAR16,
+				SpeakingRate:  speed,
+			},
+		})
+	}
+
+	return requests
+}
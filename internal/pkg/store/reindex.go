@@ -0,0 +1,74 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/metadata"
+)
+
+// audioExtensions are the file extensions Reindex considers synthesized
+// episodes, matching the formats audio.NewEncoder can produce.
+var audioExtensions = map[string]bool{
+	".mp3":  true,
+	".opus": true,
+	".flac": true,
+	".aac":  true,
+	".wav":  true,
+}
+
+// Reindex rebuilds the store from the episode metadata sidecars found
+// under dir, for recovering after the database is lost or corrupted.
+//
+// Sidecars don't carry the original article content, so every reindexed
+// record is written with an empty ContentSHA256: the next synthesis run
+// will always treat it as changed, re-synthesize once, and repopulate the
+// hash from then on. That's an acceptable cost for what's meant to be a
+// rare recovery operation, not routine use.
+func Reindex(s *Store, dir string) (int, error) {
+	count := 0
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !audioExtensions[filepath.Ext(path)] {
+			return nil
+		}
+
+		meta, ok := metadata.ReadSidecar(path)
+		if !ok {
+			return nil
+		}
+
+		if err := s.Upsert(Record{
+			FeedURL:       meta.FeedURL,
+			ItemGUID:      meta.GUID,
+			ContentSHA256: "",
+			OutputPath:    path,
+			SynthesizedAt: synthesizedAt(info, meta.PubDate),
+			Format:        filepath.Ext(path)[1:],
+		}); err != nil {
+			return err
+		}
+
+		count++
+
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+func synthesizedAt(info os.FileInfo, pubDate time.Time) time.Time {
+	if !pubDate.IsZero() {
+		return pubDate
+	}
+
+	return info.ModTime()
+}
@@ -0,0 +1,154 @@
+// Package store persists a small SQLite-backed index of already
+// synthesized episodes, keyed by (feed_url, item_guid). It lets both
+// worker paths skip re-synthesizing an item whose content hasn't
+// actually changed, instead of only checking whether a title-derived
+// output file already exists - which misses edited posts and
+// unnecessarily re-synthesizes ones whose title changed slightly between
+// fetches.
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultPath is used when Config.StorePath is empty.
+const DefaultPath = "index.db"
+
+// Record is one row of the episode index.
+type Record struct {
+	FeedURL       string
+	ItemGUID      string
+	ContentSHA256 string
+	OutputPath    string
+	SynthesizedAt time.Time
+	Voice         string
+	Speed         float64
+	Format        string
+	DurationMs    int64
+}
+
+// Store wraps the episode index database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and
+// ensures its schema exists.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening store at %s: %w", path, err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing store schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS episodes (
+	feed_url TEXT NOT NULL,
+	item_guid TEXT NOT NULL,
+	content_sha256 TEXT NOT NULL,
+	output_path TEXT NOT NULL,
+	synthesized_at DATETIME NOT NULL,
+	voice TEXT NOT NULL,
+	speed REAL NOT NULL,
+	format TEXT NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	PRIMARY KEY (feed_url, item_guid)
+);
+`
+
+// Lookup returns the stored record for (feedURL, itemGUID), if any.
+func (s *Store) Lookup(feedURL string, itemGUID string) (*Record, bool, error) {
+	row := s.db.QueryRow(
+		`SELECT feed_url, item_guid, content_sha256, output_path, synthesized_at, voice, speed, format, duration_ms
+		 FROM episodes WHERE feed_url = ? AND item_guid = ?`,
+		feedURL, itemGUID,
+	)
+
+	var r Record
+
+	err := row.Scan(&r.FeedURL, &r.ItemGUID, &r.ContentSHA256, &r.OutputPath, &r.SynthesizedAt, &r.Voice, &r.Speed, &r.Format, &r.DurationMs)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("looking up (%s, %s): %w", feedURL, itemGUID, err)
+	}
+
+	return &r, true, nil
+}
+
+// Matches reports whether r was synthesized from the same content with
+// the same voice/speed/format settings that a new request is about to
+// use, meaning re-synthesis can be skipped.
+func (r *Record) Matches(contentSHA256 string, voice string, speed float64, format string) bool {
+	return r.ContentSHA256 == contentSHA256 && r.Voice == voice && r.Speed == speed && r.Format == format
+}
+
+// Upsert records that (feedURL, itemGUID) was just synthesized.
+func (s *Store) Upsert(r Record) error {
+	_, err := s.db.Exec(
+		`INSERT INTO episodes (feed_url, item_guid, content_sha256, output_path, synthesized_at, voice, speed, format, duration_ms)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (feed_url, item_guid) DO UPDATE SET
+			content_sha256 = excluded.content_sha256,
+			output_path = excluded.output_path,
+			synthesized_at = excluded.synthesized_at,
+			voice = excluded.voice,
+			speed = excluded.speed,
+			format = excluded.format,
+			duration_ms = excluded.duration_ms`,
+		r.FeedURL, r.ItemGUID, r.ContentSHA256, r.OutputPath, r.SynthesizedAt, r.Voice, r.Speed, r.Format, r.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("upserting (%s, %s): %w", r.FeedURL, r.ItemGUID, err)
+	}
+
+	return nil
+}
+
+// Prune deletes and returns every record synthesized before cutoff. The
+// caller is responsible for removing the underlying output files.
+func (s *Store) Prune(cutoff time.Time) ([]Record, error) {
+	rows, err := s.db.Query(
+		`SELECT feed_url, item_guid, content_sha256, output_path, synthesized_at, voice, speed, format, duration_ms
+		 FROM episodes WHERE synthesized_at < ?`,
+		cutoff,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying stale records: %w", err)
+	}
+	defer rows.Close()
+
+	var stale []Record
+
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.FeedURL, &r.ItemGUID, &r.ContentSHA256, &r.OutputPath, &r.SynthesizedAt, &r.Voice, &r.Speed, &r.Format, &r.DurationMs); err != nil {
+			return nil, fmt.Errorf("scanning stale record: %w", err)
+		}
+
+		stale = append(stale, r)
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM episodes WHERE synthesized_at < ?`, cutoff); err != nil {
+		return nil, fmt.Errorf("deleting stale records: %w", err)
+	}
+
+	return stale, nil
+}
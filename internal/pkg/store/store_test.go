@@ -0,0 +1,164 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestUpsertAndLookup_RoundTrips(t *testing.T) {
+	s := openTestStore(t)
+
+	rec := Record{
+		FeedURL:       "https://example.com/feed",
+		ItemGUID:      "item-1",
+		ContentSHA256: "abc123",
+		OutputPath:    "/tmp/item-1.mp3",
+		SynthesizedAt: time.Now().Round(time.Second).UTC(),
+		Voice:         "en-US",
+		Speed:         1.0,
+		Format:        "mp3",
+		DurationMs:    1500,
+	}
+
+	if err := s.Upsert(rec); err != nil {
+		t.Fatalf("Upsert returned error: %v", err)
+	}
+
+	got, ok, err := s.Lookup(rec.FeedURL, rec.ItemGUID)
+	if err != nil {
+		t.Fatalf("Lookup returned error: %v", err)
+	}
+
+	if !ok {
+		t.Fatalf("Lookup found no record for %s/%s", rec.FeedURL, rec.ItemGUID)
+	}
+
+	if !got.Matches(rec.ContentSHA256, rec.Voice, rec.Speed, rec.Format) {
+		t.Fatalf("looked-up record %+v does not match what was upserted %+v", got, rec)
+	}
+}
+
+func TestLookup_Missing(t *testing.T) {
+	s := openTestStore(t)
+
+	_, ok, err := s.Lookup("https://example.com/feed", "no-such-item")
+	if err != nil {
+		t.Fatalf("Lookup returned error: %v", err)
+	}
+
+	if ok {
+		t.Fatalf("expected no record for an item never upserted")
+	}
+}
+
+func TestUpsert_OverwritesExistingRecord(t *testing.T) {
+	s := openTestStore(t)
+
+	feedURL, guid := "https://example.com/feed", "item-1"
+
+	if err := s.Upsert(Record{
+		FeedURL:       feedURL,
+		ItemGUID:      guid,
+		ContentSHA256: "old-hash",
+		OutputPath:    "/tmp/old.mp3",
+		SynthesizedAt: time.Now(),
+		Voice:         "en-US",
+		Speed:         1.0,
+		Format:        "mp3",
+	}); err != nil {
+		t.Fatalf("initial Upsert returned error: %v", err)
+	}
+
+	if err := s.Upsert(Record{
+		FeedURL:       feedURL,
+		ItemGUID:      guid,
+		ContentSHA256: "new-hash",
+		OutputPath:    "/tmp/new.mp3",
+		SynthesizedAt: time.Now(),
+		Voice:         "en-US",
+		Speed:         1.0,
+		Format:        "mp3",
+	}); err != nil {
+		t.Fatalf("overwriting Upsert returned error: %v", err)
+	}
+
+	got, ok, err := s.Lookup(feedURL, guid)
+	if err != nil {
+		t.Fatalf("Lookup returned error: %v", err)
+	}
+
+	if !ok {
+		t.Fatalf("expected a record after upserting")
+	}
+
+	if got.ContentSHA256 != "new-hash" || got.OutputPath != "/tmp/new.mp3" {
+		t.Fatalf("expected the second Upsert to overwrite the first, got %+v", got)
+	}
+}
+
+func TestPrune_RemovesOnlyStaleRecords(t *testing.T) {
+	s := openTestStore(t)
+
+	now := time.Now()
+
+	stale := Record{
+		FeedURL:       "https://example.com/feed",
+		ItemGUID:      "stale-item",
+		ContentSHA256: "hash",
+		OutputPath:    "/tmp/stale.mp3",
+		SynthesizedAt: now.Add(-48 * time.Hour),
+		Voice:         "en-US",
+		Speed:         1.0,
+		Format:        "mp3",
+	}
+
+	fresh := Record{
+		FeedURL:       "https://example.com/feed",
+		ItemGUID:      "fresh-item",
+		ContentSHA256: "hash",
+		OutputPath:    "/tmp/fresh.mp3",
+		SynthesizedAt: now,
+		Voice:         "en-US",
+		Speed:         1.0,
+		Format:        "mp3",
+	}
+
+	if err := s.Upsert(stale); err != nil {
+		t.Fatalf("Upsert(stale) returned error: %v", err)
+	}
+
+	if err := s.Upsert(fresh); err != nil {
+		t.Fatalf("Upsert(fresh) returned error: %v", err)
+	}
+
+	removed, err := s.Prune(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+
+	if len(removed) != 1 || removed[0].ItemGUID != "stale-item" {
+		t.Fatalf("expected Prune to remove only the stale record, got %+v", removed)
+	}
+
+	if _, ok, _ := s.Lookup(stale.FeedURL, stale.ItemGUID); ok {
+		t.Fatalf("expected stale record to be gone after Prune")
+	}
+
+	if _, ok, _ := s.Lookup(fresh.FeedURL, fresh.ItemGUID); !ok {
+		t.Fatalf("expected fresh record to survive Prune")
+	}
+}
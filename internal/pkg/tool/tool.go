@@ -0,0 +1,42 @@
+// Package tool holds small, independent helpers shared by the worker
+// pipeline: HTML stripping, Chinese-script detection and ID3 tagging.
+package tool
+
+import (
+	"html"
+	"path/filepath"
+	"regexp"
+	"unicode"
+
+	"github.com/bogem/id3v2"
+)
+
+// CHINESE_UNICODE_RANGE is used to detect whether a rune belongs to the
+// Han script, so callers can pick a Chinese voice/model for mixed-script
+// titles.
+var CHINESE_UNICODE_RANGE = []*unicode.RangeTable{unicode.Han} //nolint:revive,stylecheck
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// StripHtmlTags removes HTML markup and unescapes entities, leaving
+// plain article text suitable for feeding to a TTS engine.
+func StripHtmlTags(s string) string { //nolint:revive,stylecheck
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(s, ""))
+}
+
+// WriteID3Tag stamps the generated mp3 at path with a minimal ID3v2 tag
+// (title + album) so podcast apps and file managers show something
+// readable instead of a bare hash filename.
+func WriteID3Tag(path string, title string, album string) error {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: false})
+	if err != nil {
+		return err
+	}
+	defer tag.Close()
+
+	tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+	tag.SetTitle(title)
+	tag.SetAlbum(filepath.Base(album))
+
+	return tag.Save()
+}
@@ -3,8 +3,9 @@ package worker
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
-	"log"
+	"log/slog"
 	"math"
 	"os"
 	"path/filepath"
@@ -14,17 +15,35 @@ import (
 	"unicode"
 
 	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	gax "github.com/googleapis/gax-go/v2"
 	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"google.golang.org/grpc/status"
 
 	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
 	"github.com/KevinSJ/rss-to-podcast/internal/config"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/audio"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/chunk"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/metadata"
 	"github.com/KevinSJ/rss-to-podcast/internal/pkg/rss"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/store"
 	"github.com/KevinSJ/rss-to-podcast/internal/pkg/tool"
 	"github.com/mmcdole/gofeed"
 )
 
 const SPEECH_SYNTHESIZE_RETRY_CNT = 5
 
+// speechSynthesizer is the subset of *texttospeech.Client that
+// processSpeechGeneration depends on. Depending on the interface rather
+// than the concrete client lets tests exercise the chunking/joining
+// logic with a fake.
+type speechSynthesizer interface {
+	SynthesizeSpeech(
+		ctx context.Context,
+		req *texttospeechpb.SynthesizeSpeechRequest,
+		opts ...gax.CallOption,
+	) (*texttospeechpb.SynthesizeSpeechResponse, error)
+}
+
 type WorkerRequest struct {
 	// Item for this request
 	Item *gofeed.Item
@@ -40,6 +59,15 @@ type WorkerRequest struct {
 
 	// Speed of Synthesized Speech
 	SpeechSpeed float64
+
+	// FeedURL identifies the feed the item came from. Combined with the
+	// item's GUID, it's the key used to look the item up in the episode
+	// index.
+	FeedURL string
+
+	// Tags carries the feed's tags (e.g. its OPML folder names) through
+	// to the episode's metadata sidecar.
+	Tags []string
 }
 
 type WorkerGroup struct {
@@ -48,6 +76,9 @@ type WorkerGroup struct {
 	client        *texttospeech.Client
 	offlineClient *OfflineClient
 	waitGroup     *sync.WaitGroup
+	logger        *slog.Logger
+	encoder       audio.Encoder
+	index         *store.Store
 }
 
 type OfflineClient struct {
@@ -56,16 +87,30 @@ type OfflineClient struct {
 }
 
 func (w *WorkerGroup) Close() {
-	defer log.Printf("Closing channel")
+	defer w.logger.Info("closing channel")
 	close(w.channel)
 }
 
+// Submit enqueues req directly, bypassing feed polling. It's used by
+// callers that build a single WorkerRequest themselves, such as the
+// on-demand /tts HTTP endpoint.
+func (w *WorkerGroup) Submit(req *WorkerRequest) {
+	w.channel <- req
+}
+
+// OutputFileName returns the file name processSpeechGeneration will
+// write item's synthesized audio to, so callers can locate it once
+// synthesis completes.
+func (w *WorkerGroup) OutputFileName(item *gofeed.Item) string {
+	return outputFileName(item.Title, w.encoder.Extension())
+}
+
 func isInRange(itemPublishTime *time.Time, itemSince float64) bool {
 	return time.Since((*itemPublishTime).Local()).Hours() <= itemSince
 }
 
-func (w *WorkerGroup) CreateSpeechFromItems(feed *gofeed.Feed, direcory *string) {
-	log.Printf("feed.Title: %v\n", feed.Title)
+func (w *WorkerGroup) CreateSpeechFromItems(feed *gofeed.Feed, direcory *string, tags []string) {
+	w.logger.Info("queuing feed items", "feed", feed.Title)
 
 	itemSize := func(size int, limit int) int {
 		if size > limit {
@@ -83,72 +128,164 @@ func (w *WorkerGroup) CreateSpeechFromItems(feed *gofeed.Feed, direcory *string)
 		return lang
 	}(feed.Language)
 
+	feedURL := feed.FeedLink
+	if feedURL == "" {
+		feedURL = feed.Link
+	}
+
 	itemCnt := 0
 
 	for _, item := range feed.Items[:itemSize] {
 		if isInRange(item.PublishedParsed, w.config.ItemSince) && itemCnt < itemSize {
-			log.Printf("Adding item... title: %s", item.Title)
+			w.logger.Info("adding item", "feed", feed.Title, "item_title", item.Title, "item_guid", item.GUID)
 			w.channel <- &WorkerRequest{
 				Item:            item,
 				LanguageCode:    feedLanguage,
 				Directory:       *direcory,
 				UseNaturalVoice: w.config.UseNaturalVoice,
 				SpeechSpeed:     w.config.SpeechSpeed,
+				FeedURL:         feedURL,
+				Tags:            tags,
 			}
 			itemCnt++
 		}
 	}
 }
 
-func fileExistsAndLog(path string) bool {
+// outputFileName derives the on-disk file name for an item from its
+// title: md5.New().Sum appends the MD5 digest of empty input to the
+// title's bytes rather than hashing them, and the hex encoding of that is
+// cut to at most 50 characters, plus the encoder's extension. Titles that
+// share their first 25 bytes therefore map to the same file name.
+func outputFileName(title string, extension string) string {
+	hash := md5.New().Sum([]byte(title))
+	hashString := hex.EncodeToString(hash[:])
+
+	if len(hashString) > 50 {
+		hashString = hashString[:50]
+	}
+
+	return hashString + "." + extension
+}
+
+func fileExistsAndLog(logger *slog.Logger, path string) bool {
 	if _, err := os.Stat(path); err == nil {
-		log.Printf("File exists at path: %s\n, skip generating", path)
+		logger.Info("file exists, skipping generation", "path", path)
 		return true
 	}
 	return false
 }
 
-func processSpeechGenerationOffline(wg *sync.WaitGroup, clients *OfflineClient, workerItems chan *WorkerRequest) error {
+// contentHash hashes content, the text actually synthesized, so the
+// episode index can tell whether an item changed since it was last
+// synthesized.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// skipUnchanged looks up (feedURL, itemGUID) in index and reports whether
+// the item can be skipped because it was already synthesized from the
+// same content with the same voice/speed/format. It tolerates a nil
+// index, since indexing is optional.
+func skipUnchanged(logger *slog.Logger, index *store.Store, feedURL string, itemGUID string, hash string, voice string, speed float64, format string) bool {
+	if index == nil {
+		return false
+	}
+
+	rec, ok, err := index.Lookup(feedURL, itemGUID)
+	if err != nil {
+		logger.Warn("looking up episode index failed", "error", err)
+		return false
+	}
+
+	if ok && rec.Matches(hash, voice, speed, format) {
+		logger.Info("content unchanged, skipping generation", "path", rec.OutputPath)
+		return true
+	}
+
+	return false
+}
+
+// updateIndex records that (rec.FeedURL, rec.ItemGUID) was just
+// synthesized. It tolerates a nil index, since indexing is optional.
+func updateIndex(logger *slog.Logger, index *store.Store, rec store.Record) {
+	if index == nil {
+		return
+	}
+
+	if err := index.Upsert(rec); err != nil {
+		logger.Warn("updating episode index failed", "error", err)
+	}
+}
+
+func processSpeechGenerationOffline(workerID int, wg *sync.WaitGroup, clients *OfflineClient, encoder audio.Encoder, workerItems chan *WorkerRequest, logger *slog.Logger, index *store.Store) error {
 	defer wg.Done()
 
+	const offlineSpeed = 0.8
+
 	for workerItem := range workerItems {
 		feedItem := workerItem.Item
+		itemLogger := logger.With(
+			"worker_id", workerID,
+			"item_title", feedItem.Title,
+			"item_guid", feedItem.GUID,
+			"language", workerItem.LanguageCode,
+		)
 
-		log.Printf("Start procesing %v ", feedItem.Title)
-		hash := md5.New().Sum([]byte(feedItem.Title))
-		hashString := hex.EncodeToString(hash[:])
-		if hashSize := len(hashString); hashSize > 50 {
-			hashString = hashString[:50]
-		}
-		filePath, _ := filepath.Abs(workerItem.Directory + "/" + hashString + ".wav")
-		legacyFilePath, _ := filepath.Abs(strings.ReplaceAll(feedItem.Title, "/", "\\/") + ".mp3")
+		start := time.Now()
+		itemLogger.Info("start processing")
 
-		if fileExistsAndLog(legacyFilePath) || fileExistsAndLog(filePath) {
-			continue
-		}
-
-		content := feedItem.Title + "\n\n"
+		filePath, _ := filepath.Abs(workerItem.Directory + "/" + outputFileName(feedItem.Title, encoder.Extension()))
+		legacyFilePath, _ := filepath.Abs(strings.ReplaceAll(feedItem.Title, "/", "\\/") + ".mp3")
 
-		if len(feedItem.Content) > 0 {
-			content += tool.StripHtmlTags(feedItem.Content)
-		} else if len(feedItem.Description) > 0 {
-			content += tool.StripHtmlTags(feedItem.Description)
-		}
+		content := rss.ArticleContent(feedItem)
+		hash := contentHash(content)
 
 		client := *clients.En
+		voice := "offline-en"
+
 		for _, c := range feedItem.Title {
 			if unicode.In(c, tool.CHINESE_UNICODE_RANGE...) {
-				// return "cmn-CN"
 				client = *clients.Zh
+				voice = "offline-zh"
+
 				break
 			}
 		}
 
-		audio := client.Generate(content, 1, 0.8)
+		if skipUnchanged(itemLogger, index, workerItem.FeedURL, feedItem.GUID, hash, voice, offlineSpeed, encoder.Extension()) {
+			continue
+		}
 
-		ok := audio.Save(filePath)
-		if !ok {
-			log.Fatalf("Failed to write %s", filePath)
+		// With an index, the lookup above alone decides; an existing file
+		// only short-circuits generation when no index is in use.
+		if index == nil && (fileExistsAndLog(itemLogger, legacyFilePath) || fileExistsAndLog(itemLogger, filePath)) {
+			continue
+		}
+
+		var pcm []int16
+
+		sampleRate := 0
+
+		for _, part := range chunk.SplitText(content, chunk.DefaultMaxBytes, nil) {
+			generated := client.Generate(part, 1, offlineSpeed)
+			sampleRate = generated.SampleRate
+
+			for _, s := range generated.Samples {
+				pcm = append(pcm, float32ToInt16(s))
+			}
+		}
+
+		encoded, err := encoder.Encode(pcm, sampleRate, 1)
+		if err != nil {
+			itemLogger.Error("encoding synthesized audio failed", "error", err)
+			return err
+		}
+
+		if err := os.WriteFile(filePath, encoded, 0o644); err != nil {
+			itemLogger.Error("writing synthesized file failed", "error", err)
+			return err
 		}
 
 		fileTime := func(item *gofeed.Item) time.Time {
@@ -162,56 +299,117 @@ func processSpeechGenerationOffline(wg *sync.WaitGroup, clients *OfflineClient,
 		}(feedItem)
 
 		if err := os.Chtimes(filePath, fileTime, fileTime); err != nil {
-			log.Printf("err: %v\n", err)
+			itemLogger.Error("setting file times failed", "error", err)
 			return err
 		}
 
-		log.Printf("Finished Processing: %v, written to %v\n", feedItem.Title, filePath)
+		if err := metadata.WriteSidecar(filePath, metadata.EpisodeMeta{
+			Title:   feedItem.Title,
+			GUID:    feedItem.GUID,
+			FeedURL: workerItem.FeedURL,
+			PubDate: fileTime,
+			Tags:    workerItem.Tags,
+		}); err != nil {
+			itemLogger.Warn("writing episode metadata sidecar failed", "error", err)
+		}
+
+		durationMs := time.Since(start).Milliseconds()
+
+		updateIndex(itemLogger, index, store.Record{
+			FeedURL:       workerItem.FeedURL,
+			ItemGUID:      feedItem.GUID,
+			ContentSHA256: hash,
+			OutputPath:    filePath,
+			SynthesizedAt: time.Now(),
+			Voice:         voice,
+			Speed:         offlineSpeed,
+			Format:        encoder.Extension(),
+			DurationMs:    durationMs,
+		})
+
+		itemLogger.Info("finished processing",
+			"path", filePath,
+			"bytes_synthesized", len(encoded),
+			"duration_ms", durationMs,
+		)
 	}
 	return nil
 }
 
 // This code is taken from sample google TTS code with some modification
 // Source: https://cloud.google.com/text-to-speech/docs/libraries
-func processSpeechGeneration(wg *sync.WaitGroup, client *texttospeech.Client, workerItems chan *WorkerRequest, ctx context.Context) error {
+func processSpeechGeneration(workerID int, wg *sync.WaitGroup, client speechSynthesizer, encoder audio.Encoder, workerItems chan *WorkerRequest, ctx context.Context, logger *slog.Logger, index *store.Store) error {
 	defer wg.Done()
 
 	for workerItem := range workerItems {
 		feedItem := workerItem.Item
+		itemLogger := logger.With(
+			"worker_id", workerID,
+			"item_title", feedItem.Title,
+			"item_guid", feedItem.GUID,
+			"language", workerItem.LanguageCode,
+		)
 
-		log.Printf("Start procesing %v ", feedItem.Title)
-		hash := md5.New().Sum([]byte(feedItem.Title))
-		hashString := hex.EncodeToString(hash[:])
-		if hashSize := len(hashString); hashSize > 50 {
-			hashString = hashString[:50]
-		}
-		filePath, _ := filepath.Abs(workerItem.Directory + "/" + hashString + ".mp3")
+		start := time.Now()
+		itemLogger.Info("start processing")
+
+		filePath, _ := filepath.Abs(workerItem.Directory + "/" + outputFileName(feedItem.Title, encoder.Extension()))
 		legacyFilePath, _ := filepath.Abs(strings.ReplaceAll(feedItem.Title, "/", "\\/") + ".mp3")
 
-		if fileExistsAndLog(legacyFilePath) || fileExistsAndLog(filePath) {
+		voice := workerItem.LanguageCode
+		if workerItem.UseNaturalVoice {
+			voice += rss.NaturalVoiceSuffix
+		}
+
+		hash := contentHash(rss.ArticleContent(feedItem))
+
+		if skipUnchanged(itemLogger, index, workerItem.FeedURL, feedItem.GUID, hash, voice, workerItem.SpeechSpeed, encoder.Extension()) {
+			continue
+		}
+
+		// With an index, the lookup above alone decides; an existing file
+		// only short-circuits generation when no index is in use.
+		if index == nil && (fileExistsAndLog(itemLogger, legacyFilePath) || fileExistsAndLog(itemLogger, filePath)) {
 			continue
 		}
 
 		speechRequests := rss.GetSynthesizeSpeechRequests(feedItem, workerItem.LanguageCode, workerItem.UseNaturalVoice, workerItem.SpeechSpeed)
-		audioContent := make([]byte, 0)
+
+		var pcm []int16
+
+		sampleRate, channels := 0, 1
 
 		for _, ssr := range speechRequests {
 			var err error = nil
 			var resp *texttospeechpb.SynthesizeSpeechResponse = nil
 			for i := 0; i < SPEECH_SYNTHESIZE_RETRY_CNT; i++ {
 				if i > 0 {
-					log.Printf("Retry speech synthesize in 1 second due to error %v, count: %v", err, i)
-					time.Sleep(time.Second)
+					backoff := time.Second
+					itemLogger.Warn("retrying speech synthesize",
+						"attempt", i,
+						"grpc_status", status.Code(err).String(),
+						"backoff_ms", backoff.Milliseconds(),
+					)
+					time.Sleep(backoff)
 				}
 
 				resp, err = client.SynthesizeSpeech(ctx, ssr)
 				if err != nil {
-					log.Printf("Error Encountered, Response: %v\n", err.Error())
+					itemLogger.Warn("synthesize speech call failed", "attempt", i, "grpc_status", status.Code(err).String())
 					continue
 				}
 
 				if len(resp.AudioContent) > 0 {
-					audioContent = append(audioContent, resp.AudioContent...)
+					var chunkPCM []int16
+
+					chunkPCM, sampleRate, channels, err = audio.DecodeWAV(resp.AudioContent)
+					if err != nil {
+						itemLogger.Warn("decoding LINEAR16 response failed", "attempt", i, "error", err)
+						continue
+					}
+
+					pcm = append(pcm, chunkPCM...)
+
 					break
 				}
 			}
@@ -220,12 +418,20 @@ func processSpeechGeneration(wg *sync.WaitGroup, client *texttospeech.Client, wo
 			}
 		}
 
+		audioContent, err := encoder.Encode(pcm, sampleRate, channels)
+		if err != nil {
+			itemLogger.Error("encoding synthesized audio failed", "error", err)
+			return err
+		}
+
 		if err := os.WriteFile(filePath, audioContent, 0o755); err != nil {
-			log.Printf("err writing synthesized file: %v\n", err)
+			itemLogger.Error("writing synthesized file failed", "error", err)
 			return err
 		}
 
-		tool.WriteID3Tag(filePath, feedItem.Title, workerItem.Directory)
+		if encoder.Extension() == "mp3" {
+			tool.WriteID3Tag(filePath, feedItem.Title, workerItem.Directory)
+		}
 
 		fileTime := func(item *gofeed.Item) time.Time {
 			if item.UpdatedParsed != nil {
@@ -238,17 +444,51 @@ func processSpeechGeneration(wg *sync.WaitGroup, client *texttospeech.Client, wo
 		}(feedItem)
 
 		if err := os.Chtimes(filePath, fileTime, fileTime); err != nil {
-			log.Printf("err: %v\n", err)
+			itemLogger.Error("setting file times failed", "error", err)
 			return err
 		}
 
-		log.Printf("Finished Processing: %v, written to %v\n", feedItem.Title, filePath)
+		if err := metadata.WriteSidecar(filePath, metadata.EpisodeMeta{
+			Title:   feedItem.Title,
+			GUID:    feedItem.GUID,
+			FeedURL: workerItem.FeedURL,
+			PubDate: fileTime,
+			Tags:    workerItem.Tags,
+		}); err != nil {
+			itemLogger.Warn("writing episode metadata sidecar failed", "error", err)
+		}
+
+		durationMs := time.Since(start).Milliseconds()
+
+		updateIndex(itemLogger, index, store.Record{
+			FeedURL:       workerItem.FeedURL,
+			ItemGUID:      feedItem.GUID,
+			ContentSHA256: hash,
+			OutputPath:    filePath,
+			SynthesizedAt: time.Now(),
+			Voice:         voice,
+			Speed:         workerItem.SpeechSpeed,
+			Format:        encoder.Extension(),
+			DurationMs:    durationMs,
+		})
+
+		itemLogger.Info("finished processing",
+			"path", filePath,
+			"bytes_synthesized", len(audioContent),
+			"duration_ms", durationMs,
+		)
 	}
 
 	return nil
 }
 
-func NewWorkerGroup(config *config.Config, wg *sync.WaitGroup, client *texttospeech.Client, ctx context.Context) *WorkerGroup {
+func NewWorkerGroup(config *config.Config, wg *sync.WaitGroup, client *texttospeech.Client, ctx context.Context, logger *slog.Logger, index *store.Store) *WorkerGroup {
+	encoder, err := audio.NewEncoder(config.OutputFormat)
+	if err != nil {
+		logger.Error("invalid output_format", "error", err)
+		os.Exit(1)
+	}
+
 	channelSize := config.MaxItemPerFeed * len(config.Feeds)
 	channel := make(chan *WorkerRequest, channelSize)
 
@@ -256,7 +496,7 @@ func NewWorkerGroup(config *config.Config, wg *sync.WaitGroup, client *texttospe
 	wg.Add(workerSize)
 
 	for i := 0; i < workerSize; i++ {
-		go processSpeechGeneration(wg, client, channel, ctx)
+		go processSpeechGeneration(i, wg, client, encoder, channel, ctx, logger, index)
 	}
 
 	return &WorkerGroup{
@@ -264,10 +504,19 @@ func NewWorkerGroup(config *config.Config, wg *sync.WaitGroup, client *texttospe
 		channel:   channel,
 		client:    client,
 		waitGroup: wg,
+		logger:    logger,
+		encoder:   encoder,
+		index:     index,
 	}
 }
 
-func NewWorkerGroupOffline(config *config.Config, wg *sync.WaitGroup, clients OfflineClient, ctx context.Context) *WorkerGroup {
+func NewWorkerGroupOffline(config *config.Config, wg *sync.WaitGroup, clients OfflineClient, ctx context.Context, logger *slog.Logger, index *store.Store) *WorkerGroup {
+	encoder, err := audio.NewEncoder(config.OutputFormat)
+	if err != nil {
+		logger.Error("invalid output_format", "error", err)
+		os.Exit(1)
+	}
+
 	channelSize := config.MaxItemPerFeed * len(config.Feeds)
 	channel := make(chan *WorkerRequest, channelSize)
 
@@ -275,7 +524,7 @@ func NewWorkerGroupOffline(config *config.Config, wg *sync.WaitGroup, clients Of
 	wg.Add(workerSize)
 
 	for i := 0; i < workerSize; i++ {
-		go processSpeechGenerationOffline(wg, &clients, channel)
+		go processSpeechGenerationOffline(i, wg, &clients, encoder, channel, logger, index)
 	}
 
 	return &WorkerGroup{
@@ -283,5 +532,20 @@ func NewWorkerGroupOffline(config *config.Config, wg *sync.WaitGroup, clients Of
 		channel:       channel,
 		offlineClient: &clients,
 		waitGroup:     wg,
+		logger:        logger,
+		encoder:       encoder,
+		index:         index,
 	}
 }
+
+// float32ToInt16 converts a single PCM sample in [-1, 1], as produced by
+// the offline Sherpa models, to signed 16-bit PCM.
+func float32ToInt16(s float32) int16 {
+	if s > 1 {
+		s = 1
+	} else if s < -1 {
+		s = -1
+	}
+
+	return int16(s * 32767)
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/audio"
+	gax "github.com/googleapis/gax-go/v2"
+	"github.com/mmcdole/gofeed"
+)
+
+const fakeSampleRate = 24000
+
+// fakeSpeechClient returns one canned LINEAR16 WAV fragment per call,
+// mimicking what Google's API hands back for every chunked
+// SynthesizeSpeech request.
+type fakeSpeechClient struct {
+	calls int
+}
+
+func (f *fakeSpeechClient) SynthesizeSpeech(
+	_ context.Context,
+	_ *texttospeechpb.SynthesizeSpeechRequest,
+	_ ...gax.CallOption,
+) (*texttospeechpb.SynthesizeSpeechResponse, error) {
+	f.calls++
+
+	wavEncoder, _ := audio.NewEncoder("wav")
+	pcm := []int16{1, 2, 3, 4, 5}
+
+	data, err := wavEncoder.Encode(pcm, fakeSampleRate, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &texttospeechpb.SynthesizeSpeechResponse{AudioContent: data}, nil
+}
+
+func TestProcessSpeechGeneration_ConcatenatesDecodedPCMAcrossChunks(t *testing.T) {
+	dir := t.TempDir()
+
+	longParagraph := "This paragraph is repeated many times so the article exceeds the default per-request SSML byte limit and has to be synthesized across several chunked requests.\n\n"
+
+	content := ""
+	for i := 0; i < 40; i++ {
+		content += longParagraph
+	}
+
+	item := &gofeed.Item{
+		Title:   "a very long article that needs several chunks",
+		Content: content,
+	}
+
+	channel := make(chan *WorkerRequest, 1)
+	channel <- &WorkerRequest{
+		Item:         item,
+		Directory:    dir,
+		LanguageCode: "en-US",
+		SpeechSpeed:  1.0,
+	}
+	close(channel)
+
+	client := &fakeSpeechClient{}
+
+	encoder, err := audio.NewEncoder("wav")
+	if err != nil {
+		t.Fatalf("audio.NewEncoder: %v", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if err := processSpeechGeneration(0, &wg, client, encoder, channel, context.Background(), logger, nil); err != nil {
+		t.Fatalf("processSpeechGeneration returned error: %v", err)
+	}
+
+	if client.calls < 2 {
+		t.Fatalf("expected the article to be split into multiple chunks, got %d calls", client.calls)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.wav"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected exactly one wav output file, got %v (err=%v)", matches, err)
+	}
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("failed reading output file: %v", err)
+	}
+
+	pcm, sampleRate, channels, err := audio.DecodeWAV(data)
+	if err != nil {
+		t.Fatalf("failed decoding joined wav: %v", err)
+	}
+
+	if sampleRate != fakeSampleRate || channels != 1 {
+		t.Fatalf("unexpected format: sampleRate=%d channels=%d", sampleRate, channels)
+	}
+
+	if len(pcm) != 5*client.calls {
+		t.Fatalf("expected %d joined samples (5 per chunk x %d chunks), got %d", 5*client.calls, client.calls, len(pcm))
+	}
+}
@@ -25,42 +25,159 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	texttospeech "cloud.google.com/go/texttospeech/apiv1"
-	"github.com/KevinSJ/rss-to-podcast/internal/config"
-	"github.com/KevinSJ/rss-to-podcast/internal/helper"
+	cfg "github.com/KevinSJ/rss-to-podcast/internal/config"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/logging"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/metadata"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/podcast"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/store"
+	"github.com/KevinSJ/rss-to-podcast/internal/pkg/worker"
 	"github.com/mmcdole/gofeed"
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 )
 
-type WorkerRequest struct {
-	// Item for this request
-	Item *gofeed.Item
-
-	// Directory to which the file wil write to
-	Directory string
-
-	// Language of the item
-	LanguageCode string
-
-	// Whether to use natural Voice
-	UseNaturalVoice bool
-}
-
 func main() {
 	configPath, _ := filepath.Abs("./config.yaml")
-	config, err := config.NewConfig(configPath)
+	config, err := cfg.NewConfig(configPath)
 	if err != nil {
 		log.Fatalf("Unable to parse config file, error: %v", err)
 	}
 
+	logger, err := logging.New(config.Log)
+	if err != nil {
+		log.Fatalf("Unable to configure logger, error: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", config.CredentialPath)
+
+		ctx := context.Background()
+
+		client, err := texttospeech.NewClient(ctx)
+		if err != nil {
+			logger.Error("unable to create text-to-speech client", "error", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		index, err := store.Open(storePath(config))
+		if err != nil {
+			logger.Error("unable to open episode index", "error", err)
+			os.Exit(1)
+		}
+		defer index.Close()
+
+		var wg sync.WaitGroup
+
+		group := worker.NewWorkerGroup(config, &wg, client, ctx, logger, index)
+
+		if err := podcast.Serve(config, group, logger); err != nil {
+			logger.Error("podcast server exited", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 2 && os.Args[1] == "opml" && os.Args[2] == "export" {
+		if err := cfg.ExportOPML(os.Stdout, config.Feeds); err != nil {
+			logger.Error("opml export failed", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		index, err := store.Open(storePath(config))
+		if err != nil {
+			logger.Error("unable to open episode index", "error", err)
+			os.Exit(1)
+		}
+		defer index.Close()
+
+		count, err := store.Reindex(index, config.Server.FeedsDir)
+		if err != nil {
+			logger.Error("reindex failed", "error", err)
+			os.Exit(1)
+		}
+
+		logger.Info("reindex complete", "episodes_indexed", count)
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "prune" {
+		olderThan, ok := pruneFlagValue(os.Args)
+		if !ok {
+			logger.Error("prune requires --older-than, e.g. --older-than 30d")
+			os.Exit(1)
+		}
+
+		age, err := parseDayDuration(olderThan)
+		if err != nil {
+			logger.Error("invalid --older-than value", "value", olderThan, "error", err)
+			os.Exit(1)
+		}
+
+		index, err := store.Open(storePath(config))
+		if err != nil {
+			logger.Error("unable to open episode index", "error", err)
+			os.Exit(1)
+		}
+		defer index.Close()
+
+		stale, err := index.Prune(time.Now().Add(-age))
+		if err != nil {
+			logger.Error("prune failed", "error", err)
+			os.Exit(1)
+		}
+
+		for _, rec := range stale {
+			if err := os.Remove(rec.OutputPath); err != nil && !os.IsNotExist(err) {
+				logger.Warn("removing stale audio file failed", "path", rec.OutputPath, "error", err)
+			}
+
+			if err := os.Remove(metadata.SidecarPath(rec.OutputPath)); err != nil && !os.IsNotExist(err) {
+				logger.Warn("removing stale metadata sidecar failed", "path", rec.OutputPath, "error", err)
+			}
+		}
+
+		logger.Info("prune complete", "episodes_removed", len(stale))
+
+		return
+	}
+
+	if opmlSource, ok := opmlFlagValue(os.Args); ok {
+		feeds, err := cfg.LoadOPMLFeeds(opmlSource)
+		if err != nil {
+			logger.Error("unable to load OPML", "source", opmlSource, "error", err)
+			os.Exit(1)
+		}
+
+		config.Feeds = make([]string, 0, len(feeds))
+		config.FeedTags = make(map[string][]string, len(feeds))
+
+		for _, f := range feeds {
+			logger.Info("imported feed from OPML", "url", f.URL, "tags", f.Tags)
+			config.Feeds = append(config.Feeds, f.URL)
+
+			if len(f.Tags) > 0 {
+				config.FeedTags[f.URL] = f.Tags
+			}
+		}
+	}
+
 	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", config.CredentialPath)
 
 	fp := gofeed.NewParser()
@@ -69,25 +186,30 @@ func main() {
 
 	client, err := texttospeech.NewClient(ctx)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("unable to create text-to-speech client", "error", err)
+		os.Exit(1)
 	}
 	defer client.Close()
 
-	work := make(chan *WorkerRequest, config.MaxItemPerFeed*len(config.Feeds))
+	index, err := store.Open(storePath(config))
+	if err != nil {
+		logger.Error("unable to open episode index", "error", err)
+		os.Exit(1)
+	}
+	defer index.Close()
 
 	var wg sync.WaitGroup
-	for i := 0; i < config.ConcurrentWorkers; i++ {
-		wg.Add(1)
-		go speechSynthesizeWorker(&wg, client, &work, ctx)
-	}
+
+	group := worker.NewWorkerGroup(config, &wg, client, ctx, logger, index)
 
 	for _, _v := range config.Feeds {
 		v := _v
 		g.Go(func() error {
-			log.Printf("feed: %v\n", v)
+			logger.Info("polling feed", "feed", v)
+
 			feed, err := fp.ParseURL(v)
 			if err != nil {
-				log.Fatalf("Error GET: %v\n", err)
+				return fmt.Errorf("fetching feed %s: %w", v, err)
 			}
 
 			hasValidItems := slices.IndexFunc(feed.Items, func(item *gofeed.Item) bool {
@@ -100,92 +222,106 @@ func main() {
 
 			// create folder based on RSS update date, this will be used to store all
 			// generated mp3s.
-			dir, err := helper.CreateDirectory(*feed)
+			dir, err := feedOutputDir(feed)
 			if err != nil {
-				log.Panicf("error: %v", err)
+				return fmt.Errorf("creating output directory for feed %s: %w", v, err)
 			}
 
-			createSpeechFromItems(feed, config, &work, dir)
+			group.CreateSpeechFromItems(feed, dir, config.FeedTags[v])
 			return nil
 		})
 	}
 
 	if err := g.Wait(); err != nil {
-		log.Fatal(err.Error())
+		group.Close()
+		wg.Wait()
+		logger.Error("feed processing failed", "error", err)
+		os.Exit(1)
 	}
 
-	close(work)
+	group.Close()
 	wg.Wait()
 
-	log.Printf("Done processing all feeds")
+	logger.Info("done processing all feeds")
 }
 
-func createSpeechFromItems(feed *gofeed.Feed, config *config.Config, work *chan *WorkerRequest, direcory *string) {
-	log.Printf("feed.Title: %v\n", feed.Title)
-
-	itemSize := func(size int, limit int) int {
-		if size > limit {
-			return limit
-		}
+// feedOutputDir returns the directory synthesized episodes for feed
+// should be written to, creating it if necessary: the feed's title
+// (sanitized to be a valid path component) suffixed with its last
+// update date, so successive runs that pick up a re-dated feed land in
+// a fresh folder rather than mixing with older episodes.
+func feedOutputDir(feed *gofeed.Feed) (*string, error) {
+	updated := feed.UpdatedParsed
+	if updated == nil {
+		now := time.Now()
+		updated = &now
+	}
 
-		return size
-	}(len(feed.Items), config.MaxItemPerFeed)
+	name := fmt.Sprintf("%s_%s", sanitizeDirName(feed.Title), updated.Local().Format("2006-01-02"))
 
-	isInRange := func(itemPublishTime *time.Time) bool {
-		return time.Since((*itemPublishTime).Local()).Hours() <= config.ItemSince
+	dir, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, item := range feed.Items[:itemSize] {
-		if isInRange(item.PublishedParsed) {
-			*work <- &WorkerRequest{
-				Item:            item,
-				LanguageCode:    feed.Language,
-				Directory:       *direcory,
-				UseNaturalVoice: config.UseNaturalVoice,
-			}
-		}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
 	}
+
+	return &dir, nil
 }
 
-// This code is taken from sample google TTS code with some modification
-// Source: https://cloud.google.com/text-to-speech/docs/libraries
-func speechSynthesizeWorker(wg *sync.WaitGroup, client *texttospeech.Client, workerItems *chan *WorkerRequest, ctx context.Context) error {
-	defer wg.Done()
+// sanitizeDirName replaces path separators in s so it can be used as a
+// single directory name.
+func sanitizeDirName(s string) string {
+	return strings.ReplaceAll(s, "/", "_")
+}
 
-	for workerItem := range *workerItems {
-		feedItem := workerItem.Item
+// opmlFlagValue looks for "--opml <file|url>" among args and, if present,
+// returns its value.
+func opmlFlagValue(args []string) (string, bool) {
+	for i, a := range args {
+		if a == "--opml" && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
 
-		sanitizedTitle := strings.ReplaceAll(feedItem.Title, "/", "\\/")
-		filename := sanitizedTitle + ".mp3"
-		filepath, _ := filepath.Abs(workerItem.Directory + "/" + filename)
+	return "", false
+}
 
-		if _, err := os.Stat(filepath); err == nil {
-			log.Printf("File exists at path: %s\n, skip generating", filepath)
-			return nil
+// pruneFlagValue looks for "--older-than <duration>" among args and, if
+// present, returns its value.
+func pruneFlagValue(args []string) (string, bool) {
+	for i, a := range args {
+		if a == "--older-than" && i+1 < len(args) {
+			return args[i+1], true
 		}
+	}
 
-		log.Printf("Start procesing %v ", feedItem.Title)
-
-		speechRequests := helper.GetSynthesizeSpeechRequests(feedItem, workerItem.LanguageCode, workerItem.UseNaturalVoice)
-		audioContent := make([]byte, 0)
+	return "", false
+}
 
-		for _, ssr := range speechRequests {
-			resp, err := client.SynthesizeSpeech(ctx, ssr)
-			if err != nil {
-				log.Printf("err: %v\n", err)
-				return err
-			}
+// storePath returns config.StorePath, falling back to store.DefaultPath
+// when it's unset.
+func storePath(config *cfg.Config) string {
+	if config.StorePath == "" {
+		return store.DefaultPath
+	}
 
-			audioContent = append(audioContent, resp.AudioContent...)
-		}
+	return config.StorePath
+}
 
-		if err := os.WriteFile(filepath, audioContent, 0o644); err != nil {
-			log.Printf("err: %v\n", err)
-			return err
+// parseDayDuration parses a duration like "30d" or "12h", extending
+// time.ParseDuration with a "d" (day) unit it doesn't support natively.
+func parseDayDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q as days: %w", s, err)
 		}
 
-		log.Printf("Finished Processing: %v, written to %v\n", feedItem.Title, filepath)
+		return time.Duration(days) * 24 * time.Hour, nil
 	}
 
-	return nil
+	return time.ParseDuration(s)
 }